@@ -1,16 +1,19 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"testEM/internal/auth"
 	"testEM/internal/config"
 	"testEM/internal/delivery"
 	"testEM/internal/repository"
 	"testEM/internal/usecase"
 	"testEM/pkg/middleware"
 	"testEM/pkg/postgresql"
+	"testEM/pkg/ratelimit"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -27,6 +30,14 @@ import (
 
 // @contact.name	Alina Kuznetsova
 // @contact.email	Neeraxed@gmail.com
+
+// @securityDefinitions.apikey	ApiKeyAuth
+// @in							header
+// @name						X-API-Key
+
+// @securityDefinitions.apikey	BearerAuth
+// @in							header
+// @name						Authorization
 func main() {
 	logger, _ := zap.NewDevelopment()
 	err := godotenv.Load("./.env")
@@ -47,21 +58,105 @@ func main() {
 		zap.Time("time", time.Now()),
 	)
 
-	songStorage := repository.NewSongStorage(db, logger)
-	verseStorage := repository.NewVerseStorage(db, logger)
+	repos := repository.NewRepositories(db, logger)
+	uow := repository.NewUnitOfWork(db, logger)
 
 	cl := http.Client{}
-	externalApiClient := usecase.NewDetailClient(conf.ExternalUrl, &cl, logger)
+	externalApiClient := usecase.NewDetailClient(conf.ExternalUrl, &cl, usecase.DetailClientOptions{
+		Timeout:          conf.ExternalTimeout,
+		Retries:          conf.ExternalRetries,
+		Backoff:          conf.ExternalBackoff,
+		MaxBackoff:       conf.ExternalMaxBackoff,
+		BreakerThreshold: conf.BreakerThreshold,
+		BreakerCooldown:  conf.BreakerCooldown,
+	}, logger)
+
+	splitter, err := usecase.NewVerseSplitter(conf.VerseSplitter)
+	if err != nil {
+		logger.Fatal("Unknown verse splitter",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
 
 	//mock client for testing
 	//externalApiClient := &delivery.MockExternal{}
-	//uc := usecase.NewUsecase(songStorage, verseStorage, logger, externalApiClient)
-	uc := usecase.NewUsecase(songStorage, verseStorage, logger, externalApiClient)
-	app := delivery.NewHandler(logger, uc)
+	//uc := usecase.NewUsecase(repos, uow, logger, externalApiClient, splitter)
+	uc := usecase.NewUsecase(repos, uow, logger, externalApiClient, splitter)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	enrichmentPool := usecase.NewEnrichmentPool(uc, usecase.EnrichmentOptions{
+		Workers:      conf.EnrichmentWorkers,
+		PollInterval: conf.EnrichmentPollInterval,
+		Lease:        conf.EnrichmentLease,
+		MaxAttempts:  conf.EnrichmentMaxAttempts,
+		RetryDelay:   conf.EnrichmentRetryDelay,
+	}, logger)
+	enrichmentPool.Start(ctx)
+
+	purger := usecase.NewPurger(uc, usecase.PurgeOptions{
+		Retention: conf.PurgeRetention,
+		Interval:  conf.PurgeInterval,
+	}, logger)
+	purger.Start(ctx)
+
+	importer := usecase.NewImporter(uc, usecase.ImportOptions{
+		Workers:   conf.ImportWorkers,
+		BatchSize: conf.ImportBatchSize,
+		MaxRows:   conf.ImportMaxRows,
+	}, logger)
+
+	authenticator, err := auth.NewAuthenticator(auth.Options{
+		APIKeys:          conf.APIKeys,
+		JWTSecret:        conf.JWTSecret,
+		JWTPublicKeyFile: conf.JWTPublicKeyFile,
+		JWKSFile:         conf.JWKSFile,
+		Issuer:           conf.JWTIssuer,
+		Audience:         conf.JWTAudience,
+		AnonymousRole:    conf.AnonymousRole,
+	})
+	if err != nil {
+		logger.Fatal("Failed to set up authentication",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+
+	limits, err := ratelimit.ParseLimits(conf.RateLimits)
+	if err != nil {
+		logger.Fatal("Failed to read rate limits",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+	var limitStore ratelimit.Store
+	switch conf.RateLimitStore {
+	case "memory":
+		limitStore = ratelimit.NewMemoryStore()
+	case "postgres":
+		limitStore = repository.NewRateLimitStorage(db, logger)
+	default:
+		logger.Fatal("Unknown rate limit store",
+			zap.String("store", conf.RateLimitStore),
+			zap.Time("time", time.Now()),
+		)
+	}
+	limiter := ratelimit.NewLimiter(limitStore, limits)
+
+	timeouts, err := delivery.ParseRouteTimeouts(conf.RouteTimeouts)
+	if err != nil {
+		logger.Fatal("Failed to read route timeouts",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+
+	app := delivery.NewHandler(logger, uc, importer, authenticator, limiter, timeouts)
 	onion := middleware.NewOnion(logger)
 	onion.AppendMiddleware(
 		onion.Timer,
-		onion.LogRequestResponse)
+		onion.LogRequestResponse,
+		onion.RequestID)
 	router := app.ApplyRoutes(onion)
 	go func() {
 		err = http.ListenAndServe(conf.Port, router)
@@ -73,6 +168,9 @@ func main() {
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
 	<-sigs
+	cancel()
+	enrichmentPool.Wait()
+	purger.Wait()
 	if err != nil {
 		logger.Fatal("Server died",
 			zap.String("message", err.Error()),
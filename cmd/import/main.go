@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testEM/internal/config"
+	"testEM/internal/entities"
+	"testEM/internal/repository"
+	"testEM/internal/usecase"
+	"testEM/pkg/postgresql"
+	"time"
+
+	"github.com/joho/godotenv"
+	"go.uber.org/zap"
+)
+
+// Import adds songs from a csv or ndjson file the same way as POST /songs/import
+// and prints the report to stdout. Exits with 1 when the file can't be imported
+// and with 2 when some of the rows failed.
+//
+//	import [-format csv|ndjson] [-splitter blank|crlf|sections] [-env .env] FILE
+func main() {
+	format := flag.String("format", "", "format of the file: csv or ndjson, taken from extension by default")
+	splitterName := flag.String("splitter", "", "verse splitter: blank, crlf or sections, VERSESPLITTER by default")
+	envFile := flag.String("env", "./.env", "path to env file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] FILE\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	path := flag.Arg(0)
+
+	logger, _ := zap.NewDevelopment()
+	if err := godotenv.Load(*envFile); err != nil {
+		logger.Fatal("Failed to load .env file",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			*format = entities.ImportFormatCSV
+		case ".ndjson", ".jsonl":
+			*format = entities.ImportFormatNDJSON
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		logger.Fatal("Failed to open import file",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+	defer file.Close()
+
+	conf := config.ReadConfig()
+	db := postgresql.NewConnection(conf.PostgresDSN, logger)
+	defer db.Close()
+
+	cl := http.Client{}
+	externalApiClient := usecase.NewDetailClient(conf.ExternalUrl, &cl, usecase.DetailClientOptions{
+		Timeout:          conf.ExternalTimeout,
+		Retries:          conf.ExternalRetries,
+		Backoff:          conf.ExternalBackoff,
+		MaxBackoff:       conf.ExternalMaxBackoff,
+		BreakerThreshold: conf.BreakerThreshold,
+		BreakerCooldown:  conf.BreakerCooldown,
+	}, logger)
+
+	splitter, err := usecase.NewVerseSplitter(conf.VerseSplitter)
+	if err != nil {
+		logger.Fatal("Unknown verse splitter",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+
+	uc := usecase.NewUsecase(repository.NewRepositories(db, logger), repository.NewUnitOfWork(db, logger), logger, externalApiClient, splitter)
+	importer := usecase.NewImporter(uc, usecase.ImportOptions{
+		Workers:   conf.ImportWorkers,
+		BatchSize: conf.ImportBatchSize,
+		MaxRows:   conf.ImportMaxRows,
+	}, logger)
+
+	// interrupting the import rolls back the batch being stored
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	report, err := importer.Import(ctx, file, *format, *splitterName, entities.Caller{Actor: entities.ActorImport})
+	if err != nil {
+		logger.Error("Failed to import songs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		db.Close()
+		os.Exit(1)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(report); err != nil {
+		logger.Error("Failed to write report",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+
+	if report.Failed > 0 {
+		db.Close()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"testEM/internal/entities"
+	"unicode/utf8"
+)
+
+const (
+	RoleReader = "reader"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// roleRank orders roles, a role grants everything the lower ones do.
+var roleRank = map[string]int{
+	RoleReader: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+const (
+	MethodAPIKey    = "apikey"
+	MethodJWT       = "jwt"
+	MethodAnonymous = "anonymous"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// maxSubjectLength bounds subjects in characters, they are stored as the
+// actor of song revisions and audit entries.
+const maxSubjectLength = 256
+
+// Principal is the authenticated caller of the request.
+type Principal struct {
+	Subject string
+	Role    string
+	Method  string
+}
+
+// HasRole reports whether the principal's role grants the given one.
+func (p *Principal) HasRole(role string) bool {
+	return roleRank[p.Role] >= roleRank[role]
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal put into the context by the middleware.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+type Options struct {
+	// APIKeys lists key:subject:role entries separated by commas.
+	APIKeys          string
+	JWTSecret        string
+	JWTPublicKeyFile string
+	JWKSFile         string
+	Issuer           string
+	Audience         string
+	// AnonymousRole is given to requests without credentials, none when empty.
+	AnonymousRole string
+}
+
+// Authenticator checks API keys and HS256/RS256 bearer tokens.
+type Authenticator struct {
+	apiKeys       map[[sha256.Size]byte]Principal
+	hmacKeys      map[string][]byte
+	rsaKeys       map[string]*rsa.PublicKey
+	issuer        string
+	audience      string
+	anonymousRole string
+}
+
+func NewAuthenticator(opts Options) (*Authenticator, error) {
+	a := &Authenticator{
+		hmacKeys:      make(map[string][]byte),
+		rsaKeys:       make(map[string]*rsa.PublicKey),
+		issuer:        opts.Issuer,
+		audience:      opts.Audience,
+		anonymousRole: opts.AnonymousRole,
+	}
+	if a.anonymousRole != "" && !ValidRole(a.anonymousRole) {
+		return nil, fmt.Errorf("unknown anonymous role %q", a.anonymousRole)
+	}
+
+	var err error
+	if a.apiKeys, err = parseAPIKeys(opts.APIKeys); err != nil {
+		return nil, err
+	}
+	if opts.JWTSecret != "" {
+		a.hmacKeys[""] = []byte(opts.JWTSecret)
+	}
+	if opts.JWTPublicKeyFile != "" {
+		key, err := loadPublicKey(opts.JWTPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		a.rsaKeys[""] = key
+	}
+	if opts.JWKSFile != "" {
+		if err := a.loadJWKS(opts.JWKSFile); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// Authenticate returns the principal of the request, an API key takes
+// precedence over a bearer token.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		p, ok := a.apiKeys[sha256.Sum256([]byte(key))]
+		if !ok {
+			return nil, &entities.UnauthorizedError{Message: "unknown API key"}
+		}
+		return &p, nil
+	}
+
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return nil, &entities.UnauthorizedError{Message: "bearer token expected"}
+		}
+		return a.verifyToken(strings.TrimSpace(token))
+	}
+
+	if a.anonymousRole != "" {
+		return &Principal{Subject: entities.ActorAnonymous, Role: a.anonymousRole, Method: MethodAnonymous}, nil
+	}
+	return nil, &entities.UnauthorizedError{Message: "credentials required"}
+}
+
+func parseAPIKeys(s string) (map[[sha256.Size]byte]Principal, error) {
+	keys := make(map[[sha256.Size]byte]Principal)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("API key entry must be key:subject:role")
+		}
+		if !ValidRole(parts[2]) {
+			return nil, fmt.Errorf("unknown role %q of API key for %s", parts[2], parts[1])
+		}
+		if utf8.RuneCountInString(parts[1]) > maxSubjectLength {
+			return nil, fmt.Errorf("subject of API key must be at most %d characters", maxSubjectLength)
+		}
+		keys[sha256.Sum256([]byte(parts[0]))] = Principal{Subject: parts[1], Role: parts[2], Method: MethodAPIKey}
+	}
+	return keys, nil
+}
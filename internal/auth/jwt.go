@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testEM/internal/entities"
+	"time"
+	"unicode/utf8"
+)
+
+// clockSkew is tolerated when checking exp and nbf of tokens.
+const clockSkew = 30 * time.Second
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type tokenClaims struct {
+	Subject   string          `json:"sub"`
+	Role      string          `json:"role"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+}
+
+// verifyToken checks the signature and claims of a compact JWS. Tokens must
+// expire and carry the subject and one of the roles.
+func (a *Authenticator) verifyToken(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, &entities.UnauthorizedError{Message: "malformed token"}
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, &entities.UnauthorizedError{Message: "malformed token header"}
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, &entities.UnauthorizedError{Message: "malformed token signature"}
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	switch header.Alg {
+	case "HS256":
+		for _, key := range candidates(a.hmacKeys, header.Kid) {
+			mac := hmac.New(sha256.New, key)
+			mac.Write(signed)
+			if hmac.Equal(mac.Sum(nil), sig) {
+				verified = true
+				break
+			}
+		}
+	case "RS256":
+		digest := sha256.Sum256(signed)
+		for _, key := range candidates(a.rsaKeys, header.Kid) {
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil {
+				verified = true
+				break
+			}
+		}
+	default:
+		return nil, &entities.UnauthorizedError{Message: "unsupported token algorithm " + header.Alg}
+	}
+	if !verified {
+		return nil, &entities.UnauthorizedError{Message: "invalid token signature"}
+	}
+
+	var claims tokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, &entities.UnauthorizedError{Message: "malformed token claims"}
+	}
+	if err := a.checkClaims(&claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return &Principal{Subject: claims.Subject, Role: claims.Role, Method: MethodJWT}, nil
+}
+
+func (a *Authenticator) checkClaims(claims *tokenClaims, now time.Time) error {
+	if claims.ExpiresAt == nil {
+		return &entities.UnauthorizedError{Message: "token without expiration"}
+	}
+	if now.Add(-clockSkew).After(unixTime(*claims.ExpiresAt)) {
+		return &entities.UnauthorizedError{Message: "token expired"}
+	}
+	if claims.NotBefore != nil && now.Add(clockSkew).Before(unixTime(*claims.NotBefore)) {
+		return &entities.UnauthorizedError{Message: "token not valid yet"}
+	}
+	if a.issuer != "" && claims.Issuer != a.issuer {
+		return &entities.UnauthorizedError{Message: "unexpected token issuer"}
+	}
+	if a.audience != "" && !hasAudience(claims.Audience, a.audience) {
+		return &entities.UnauthorizedError{Message: "unexpected token audience"}
+	}
+	if claims.Subject == "" {
+		return &entities.UnauthorizedError{Message: "token without subject"}
+	}
+	if utf8.RuneCountInString(claims.Subject) > maxSubjectLength {
+		return &entities.UnauthorizedError{Message: "token subject is too long"}
+	}
+	if !ValidRole(claims.Role) {
+		return &entities.UnauthorizedError{Message: "token without known role"}
+	}
+	return nil
+}
+
+// candidates returns the key with the id, or every key when the token
+// doesn't name one.
+func candidates[K any](keys map[string]K, kid string) []K {
+	if kid != "" {
+		if key, ok := keys[kid]; ok {
+			return []K{key}
+		}
+		return nil
+	}
+	res := make([]K, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, key)
+	}
+	return res
+}
+
+// hasAudience accepts aud as a single string or an array of them.
+func hasAudience(raw json.RawMessage, audience string) bool {
+	var single string
+	if json.Unmarshal(raw, &single) == nil {
+		return single == audience
+	}
+	var list []string
+	if json.Unmarshal(raw, &list) != nil {
+		return false
+	}
+	for _, aud := range list {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
@@ -0,0 +1,295 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-secret"
+
+type testSigner struct {
+	hmacKey []byte
+	rsaKey  *rsa.PrivateKey
+}
+
+// sign builds a compact JWS with the header and claims, signing it as the
+// header's alg says.
+func (s testSigner) sign(t *testing.T, header map[string]any, claims map[string]any) string {
+	t.Helper()
+	segment := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := segment(header) + "." + segment(claims)
+
+	var sig []byte
+	switch header["alg"] {
+	case "HS256":
+		mac := hmac.New(sha256.New, s.hmacKey)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case "RS256":
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, s.rsaKey, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		sig = []byte("signature")
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func validClaims(mod func(c map[string]any)) map[string]any {
+	c := map[string]any{
+		"sub":  "alice",
+		"role": RoleEditor,
+		"iss":  "issuer",
+		"aud":  "api",
+		"exp":  time.Now().Add(time.Minute).Unix(),
+	}
+	if mod != nil {
+		mod(c)
+	}
+	return c
+}
+
+func TestVerifyToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)})
+
+	a := &Authenticator{
+		hmacKeys: map[string][]byte{"": []byte(testSecret), "hs1": []byte("kid-secret")},
+		rsaKeys:  map[string]*rsa.PublicKey{"rs1": &rsaKey.PublicKey},
+		issuer:   "issuer",
+		audience: "api",
+	}
+	// only an RSA key is configured, its PEM must not work as an HMAC secret
+	rsaOnly := &Authenticator{
+		hmacKeys: map[string][]byte{},
+		rsaKeys:  map[string]*rsa.PublicKey{"": &rsaKey.PublicKey},
+	}
+
+	hs := testSigner{hmacKey: []byte(testSecret)}
+	rs := testSigner{rsaKey: rsaKey}
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		auth    *Authenticator
+		token   string
+		wantErr bool
+	}{
+		{
+			name:  "hs256 with configured secret",
+			auth:  a,
+			token: hs.sign(t, map[string]any{"alg": "HS256"}, validClaims(nil)),
+		},
+		{
+			name:  "hs256 with kid",
+			auth:  a,
+			token: testSigner{hmacKey: []byte("kid-secret")}.sign(t, map[string]any{"alg": "HS256", "kid": "hs1"}, validClaims(nil)),
+		},
+		{
+			name:  "rs256 with kid",
+			auth:  a,
+			token: rs.sign(t, map[string]any{"alg": "RS256", "kid": "rs1"}, validClaims(nil)),
+		},
+		{
+			name:    "alg none",
+			auth:    a,
+			token:   hs.sign(t, map[string]any{"alg": "none"}, validClaims(nil)),
+			wantErr: true,
+		},
+		{
+			name:    "unsupported alg",
+			auth:    a,
+			token:   hs.sign(t, map[string]any{"alg": "HS512"}, validClaims(nil)),
+			wantErr: true,
+		},
+		{
+			name:    "hs256 signed with rsa public key",
+			auth:    rsaOnly,
+			token:   testSigner{hmacKey: publicPEM}.sign(t, map[string]any{"alg": "HS256"}, validClaims(nil)),
+			wantErr: true,
+		},
+		{
+			name:    "rs256 kid pointing to hmac key",
+			auth:    a,
+			token:   rs.sign(t, map[string]any{"alg": "RS256", "kid": "hs1"}, validClaims(nil)),
+			wantErr: true,
+		},
+		{
+			name:    "unknown kid",
+			auth:    a,
+			token:   hs.sign(t, map[string]any{"alg": "HS256", "kid": "missing"}, validClaims(nil)),
+			wantErr: true,
+		},
+		{
+			name:    "bad hmac signature",
+			auth:    a,
+			token:   testSigner{hmacKey: []byte("wrong")}.sign(t, map[string]any{"alg": "HS256"}, validClaims(nil)),
+			wantErr: true,
+		},
+		{
+			name:    "bad rsa signature",
+			auth:    a,
+			token:   testSigner{rsaKey: otherKey}.sign(t, map[string]any{"alg": "RS256", "kid": "rs1"}, validClaims(nil)),
+			wantErr: true,
+		},
+		{
+			name:  "expired within leeway",
+			auth:  a,
+			token: hs.sign(t, map[string]any{"alg": "HS256"}, validClaims(func(c map[string]any) { c["exp"] = now.Add(-clockSkew / 2).Unix() })),
+		},
+		{
+			name:    "expired past leeway",
+			auth:    a,
+			token:   hs.sign(t, map[string]any{"alg": "HS256"}, validClaims(func(c map[string]any) { c["exp"] = now.Add(-2 * clockSkew).Unix() })),
+			wantErr: true,
+		},
+		{
+			name:    "without exp",
+			auth:    a,
+			token:   hs.sign(t, map[string]any{"alg": "HS256"}, validClaims(func(c map[string]any) { delete(c, "exp") })),
+			wantErr: true,
+		},
+		{
+			name:  "nbf within leeway",
+			auth:  a,
+			token: hs.sign(t, map[string]any{"alg": "HS256"}, validClaims(func(c map[string]any) { c["nbf"] = now.Add(clockSkew / 2).Unix() })),
+		},
+		{
+			name:    "nbf past leeway",
+			auth:    a,
+			token:   hs.sign(t, map[string]any{"alg": "HS256"}, validClaims(func(c map[string]any) { c["nbf"] = now.Add(2 * clockSkew).Unix() })),
+			wantErr: true,
+		},
+		{
+			name:  "audience in list",
+			auth:  a,
+			token: hs.sign(t, map[string]any{"alg": "HS256"}, validClaims(func(c map[string]any) { c["aud"] = []string{"other", "api"} })),
+		},
+		{
+			name:    "wrong audience",
+			auth:    a,
+			token:   hs.sign(t, map[string]any{"alg": "HS256"}, validClaims(func(c map[string]any) { c["aud"] = "other" })),
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience in list",
+			auth:    a,
+			token:   hs.sign(t, map[string]any{"alg": "HS256"}, validClaims(func(c map[string]any) { c["aud"] = []string{"other"} })),
+			wantErr: true,
+		},
+		{
+			name:    "wrong issuer",
+			auth:    a,
+			token:   hs.sign(t, map[string]any{"alg": "HS256"}, validClaims(func(c map[string]any) { c["iss"] = "other" })),
+			wantErr: true,
+		},
+		{
+			name:    "subject too long",
+			auth:    a,
+			token:   hs.sign(t, map[string]any{"alg": "HS256"}, validClaims(func(c map[string]any) { c["sub"] = strings.Repeat("a", maxSubjectLength+1) })),
+			wantErr: true,
+		},
+		{
+			name:    "unknown role",
+			auth:    a,
+			token:   hs.sign(t, map[string]any{"alg": "HS256"}, validClaims(func(c map[string]any) { c["role"] = "root" })),
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			auth:    a,
+			token:   "not.a-token",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := tt.auth.verifyToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (p.Subject != "alice" || p.Role != RoleEditor || p.Method != MethodJWT) {
+				t.Errorf("verifyToken() = %+v", p)
+			}
+		})
+	}
+}
+
+func TestLoadJWKS(t *testing.T) {
+	key := base64.RawURLEncoding.EncodeToString([]byte("jwks-secret-of-at-least-32-bytes"))
+	tests := []struct {
+		name    string
+		jwks    string
+		wantErr bool
+	}{
+		{
+			name: "keys with kid",
+			jwks: `{"keys":[{"kty":"oct","kid":"k1","k":"` + key + `"},{"kty":"oct","use":"enc","k":"` + key + `"}]}`,
+		},
+		{
+			name:    "key without kid",
+			jwks:    `{"keys":[{"kty":"oct","k":"` + key + `"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "empty symmetric key",
+			jwks:    `{"keys":[{"kty":"oct","kid":"k1"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "short symmetric key",
+			jwks:    `{"keys":[{"kty":"oct","kid":"k1","k":"` + base64.RawURLEncoding.EncodeToString([]byte("short")) + `"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid rsa key",
+			jwks:    `{"keys":[{"kty":"RSA","kid":"r1","n":"","e":"AQAB"}]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "jwks.json")
+			if err := os.WriteFile(path, []byte(tt.jwks), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			a, err := NewAuthenticator(Options{JWTSecret: testSecret, JWKSFile: path})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewAuthenticator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(a.hmacKeys[""]) != testSecret {
+				t.Errorf("configured secret replaced by %q", a.hmacKeys[""])
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// minHMACKeySize is the shortest symmetric key accepted from a JWK set, as
+// long as the output of HS256.
+const minHMACKeySize = 32
+
+// loadPublicKey reads an RSA public key from a PEM file in PKIX or PKCS #1 form.
+func loadPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("key in %s is not an RSA key", path)
+	}
+	return rsaKey, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// loadJWKS adds RSA and symmetric keys of a local JWK set, keys meant for
+// encryption are skipped. Every key must have a kid so that it can't replace
+// the configured secret or public key, symmetric keys must be long enough not
+// to be guessed.
+func (a *Authenticator) loadJWKS(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("failed to read JWKS %s: %w", path, err)
+	}
+
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if k.Kid == "" {
+			return fmt.Errorf("JWK without kid in %s", path)
+		}
+		switch k.Kty {
+		case "RSA":
+			key, err := k.rsaKey()
+			if err != nil {
+				return fmt.Errorf("failed to read JWK %q: %w", k.Kid, err)
+			}
+			a.rsaKeys[k.Kid] = key
+		case "oct":
+			key, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil {
+				return fmt.Errorf("failed to read JWK %q: %w", k.Kid, err)
+			}
+			if len(key) < minHMACKeySize {
+				return fmt.Errorf("JWK %q is shorter than %d bytes", k.Kid, minHMACKeySize)
+			}
+			a.hmacKeys[k.Kid] = key
+		}
+	}
+	return nil
+}
+
+func (k jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA key")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
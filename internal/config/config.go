@@ -2,12 +2,49 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
 	PostgresDSN string
 	ExternalUrl string
 	Port        string
+
+	ExternalTimeout    time.Duration
+	ExternalRetries    int
+	ExternalBackoff    time.Duration
+	ExternalMaxBackoff time.Duration
+	BreakerThreshold   int
+	BreakerCooldown    time.Duration
+
+	EnrichmentWorkers      int
+	EnrichmentPollInterval time.Duration
+	EnrichmentLease        time.Duration
+	EnrichmentMaxAttempts  int
+	EnrichmentRetryDelay   time.Duration
+
+	ImportWorkers   int
+	ImportBatchSize int
+	ImportMaxRows   int
+
+	VerseSplitter string
+
+	PurgeRetention time.Duration
+	PurgeInterval  time.Duration
+
+	APIKeys          string
+	JWTSecret        string
+	JWTPublicKeyFile string
+	JWKSFile         string
+	JWTIssuer        string
+	JWTAudience      string
+	AnonymousRole    string
+
+	RateLimits     string
+	RateLimitStore string
+
+	RouteTimeouts string
 }
 
 func ReadConfig() *Config {
@@ -15,5 +52,71 @@ func ReadConfig() *Config {
 		PostgresDSN: os.Getenv("POSTGRESDSN"),
 		ExternalUrl: os.Getenv("EXTERNALURL"),
 		Port:        os.Getenv("PORT"),
+
+		ExternalTimeout:    durationEnv("EXTERNALTIMEOUT", 5*time.Second),
+		ExternalRetries:    intEnv("EXTERNALRETRIES", 3),
+		ExternalBackoff:    durationEnv("EXTERNALBACKOFF", 200*time.Millisecond),
+		ExternalMaxBackoff: durationEnv("EXTERNALMAXBACKOFF", 5*time.Second),
+		BreakerThreshold:   intEnv("BREAKERTHRESHOLD", 5),
+		BreakerCooldown:    durationEnv("BREAKERCOOLDOWN", 30*time.Second),
+
+		EnrichmentWorkers:      intEnv("ENRICHMENTWORKERS", 4),
+		EnrichmentPollInterval: durationEnv("ENRICHMENTPOLLINTERVAL", 2*time.Second),
+		EnrichmentLease:        durationEnv("ENRICHMENTLEASE", time.Minute),
+		EnrichmentMaxAttempts:  intEnv("ENRICHMENTMAXATTEMPTS", 5),
+		EnrichmentRetryDelay:   durationEnv("ENRICHMENTRETRYDELAY", 10*time.Second),
+
+		ImportWorkers:   intEnv("IMPORTWORKERS", 4),
+		ImportBatchSize: intEnv("IMPORTBATCHSIZE", 100),
+		ImportMaxRows:   intEnv("IMPORTMAXROWS", 10000),
+
+		VerseSplitter: stringEnv("VERSESPLITTER", "crlf"),
+
+		PurgeRetention: positiveDurationEnv("PURGERETENTION", 30*24*time.Hour),
+		PurgeInterval:  positiveDurationEnv("PURGEINTERVAL", time.Hour),
+
+		APIKeys:          os.Getenv("APIKEYS"),
+		JWTSecret:        os.Getenv("JWTSECRET"),
+		JWTPublicKeyFile: os.Getenv("JWTPUBLICKEYFILE"),
+		JWKSFile:         os.Getenv("JWKSFILE"),
+		JWTIssuer:        os.Getenv("JWTISSUER"),
+		JWTAudience:      os.Getenv("JWTAUDIENCE"),
+		AnonymousRole:    os.Getenv("ANONYMOUSROLE"),
+
+		RateLimits:     stringEnv("RATELIMITS", "default=20/s:40,POST /api/v1/songs=30/m:10"),
+		RateLimitStore: stringEnv("RATELIMITSTORE", "memory"),
+
+		RouteTimeouts: stringEnv("ROUTETIMEOUTS", "default=30s,POST /api/v1/songs/import=10m,GET /api/v1/songs/export=none"),
+	}
+}
+
+func intEnv(key string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func stringEnv(key string, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// positiveDurationEnv falls back to def for zero and negative durations too.
+func positiveDurationEnv(key string, def time.Duration) time.Duration {
+	if v := durationEnv(key, def); v > 0 {
+		return v
 	}
+	return def
 }
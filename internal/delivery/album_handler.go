@@ -0,0 +1,283 @@
+package delivery
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testEM/internal/entities"
+	"testEM/internal/validation"
+	"time"
+
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+// @Summary      Get albums
+// @Description  get albums, title filters by substring ignoring case
+// @Produce      json
+// @Success      200  {object} entities.AlbumsWrapper
+// @Failure      400  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /albums [get]
+func (h *handler) GetAlbums(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	searchOptions := entities.AlbumSearchOptions{}
+	if err := validation.DecodeQuery(r.URL.Query(), &searchOptions); err != nil {
+		h.log.Error("Failed to read search options",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+
+	albums, err := h.uc.GetAlbums(r.Context(), searchOptions)
+	if err != nil {
+		h.log.Error("Failed to get albums",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, albums)
+}
+
+// @Summary      Get album
+// @Description  get album with specified id and its tracks
+// @Produce      json
+// @Success      200  {object} entities.Album
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /albums/{id} [get]
+func (h *handler) GetAlbum(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	albumID := chi.URLParam(r, "id")
+
+	a, err := h.uc.GetAlbum(r.Context(), albumID)
+	if err != nil {
+		h.log.Error("Failed to get album",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, a)
+}
+
+// @Summary      Add album
+// @Description  add album of group
+// @Produce      json
+// @Success      201  {object} entities.Album
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /albums [post]
+func (h *handler) AddAlbum(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	dto := entities.AddAlbumDTO{}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &dto)
+	}
+	if err != nil {
+		h.log.Error("Failed to read body",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("body", err))
+		return
+	}
+
+	a, err := h.uc.AddAlbum(r.Context(), dto, caller(r))
+	if err != nil {
+		h.log.Error("Failed to add album",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusCreated, a)
+}
+
+// @Summary      Patch album
+// @Description  update album with specified id
+// @Produce      json
+// @Success      200  {object} entities.Album
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /albums/{id} [patch]
+func (h *handler) PatchAlbum(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	albumID := chi.URLParam(r, "id")
+
+	dto := entities.PatchAlbumDTO{}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &dto)
+	}
+	if err != nil {
+		h.log.Error("Failed to read body",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("body", err))
+		return
+	}
+
+	a, err := h.uc.PatchAlbum(r.Context(), albumID, dto, caller(r))
+	if err != nil {
+		h.log.Error("Failed to update album",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, a)
+}
+
+// @Summary      Delete album
+// @Description  delete album with specified id, its songs are kept
+// @Produce      json
+// @Success      204  {object} nil
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /albums/{id} [delete]
+func (h *handler) DeleteAlbum(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	albumID := chi.URLParam(r, "id")
+
+	err := h.uc.DeleteAlbum(r.Context(), albumID, caller(r))
+	if err != nil {
+		h.log.Error("Failed to delete album",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Add track
+// @Description  add song to album at specified track number, following tracks are shifted down; appends when number is omitted
+// @Produce      json
+// @Success      201  {object} entities.Album
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      409  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /albums/{id}/tracks [post]
+func (h *handler) AddTrack(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	albumID := chi.URLParam(r, "id")
+
+	dto := entities.AddTrackDTO{}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &dto)
+	}
+	if err != nil {
+		h.log.Error("Failed to read body",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("body", err))
+		return
+	}
+
+	a, err := h.uc.AddTrack(r.Context(), albumID, dto, caller(r))
+	if err != nil {
+		h.log.Error("Failed to add track",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusCreated, a)
+}
+
+// @Summary      Remove track
+// @Description  remove song from album, following tracks are shifted up
+// @Produce      json
+// @Success      204  {object} nil
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /albums/{id}/tracks/{songId} [delete]
+func (h *handler) RemoveTrack(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	albumID := chi.URLParam(r, "id")
+	songID := chi.URLParam(r, "songId")
+
+	err := h.uc.RemoveTrack(r.Context(), albumID, songID, caller(r))
+	if err != nil {
+		h.log.Error("Failed to remove track",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Move track
+// @Description  move song to another track number, tracks in between are renumbered
+// @Produce      json
+// @Success      200  {object} entities.Album
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /albums/{id}/tracks/{songId}/move [post]
+func (h *handler) MoveTrack(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	albumID := chi.URLParam(r, "id")
+	songID := chi.URLParam(r, "songId")
+
+	dto := entities.MoveTrackDTO{}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &dto)
+	}
+	if err != nil {
+		h.log.Error("Failed to read body",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("body", err))
+		return
+	}
+
+	a, err := h.uc.MoveTrack(r.Context(), albumID, songID, dto, caller(r))
+	if err != nil {
+		h.log.Error("Failed to move track",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, a)
+}
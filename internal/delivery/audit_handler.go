@@ -0,0 +1,43 @@
+package delivery
+
+import (
+	"net/http"
+	"testEM/internal/entities"
+	"testEM/internal/validation"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// @Summary      Get audit log
+// @Description  get recorded mutations newest first, filtered by actor, resource, resource id and RFC 3339 time range
+// @Produce      json
+// @Success      200  {object} entities.AuditWrapper
+// @Failure      400  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /audit [get]
+func (h *handler) GetAudit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	searchOptions := entities.AuditSearchOptions{}
+	if err := validation.DecodeQuery(r.URL.Query(), &searchOptions); err != nil {
+		h.log.Error("Failed to read search options",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+
+	entries, err := h.uc.GetAudit(r.Context(), searchOptions)
+	if err != nil {
+		h.log.Error("Failed get audit entries",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, entries)
+}
@@ -0,0 +1,58 @@
+package delivery
+
+import (
+	"net"
+	"net/http"
+	"testEM/internal/auth"
+	"testEM/internal/entities"
+	"testEM/pkg/middleware"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// require authenticates the request and lets it through when the principal
+// has the role, the principal is put into the request context.
+func (h *handler) require(role string) middleware.Function {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			p, err := h.auth.Authenticate(r)
+			if err != nil {
+				h.log.Info("Failed to authenticate request",
+					zap.String("message", err.Error()),
+					zap.Time("time", time.Now()),
+				)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="testEM"`)
+				ReturnHttpError(w, r, err)
+				return
+			}
+			if !p.HasRole(role) {
+				h.log.Info("Request denied",
+					zap.String("subject", p.Subject),
+					zap.String("role", p.Role),
+					zap.String("required", role),
+					zap.Time("time", time.Now()),
+				)
+				ReturnHttpError(w, r, &entities.ForbiddenError{Role: role})
+				return
+			}
+			next(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+		}
+	}
+}
+
+// caller names who makes the change and where the request came from, it is
+// recorded in song history and the audit log.
+func caller(r *http.Request) entities.Caller {
+	c := entities.Caller{
+		Actor:     entities.ActorAnonymous,
+		RequestID: middleware.RequestIDFrom(r.Context()),
+	}
+	if p, ok := auth.PrincipalFrom(r.Context()); ok {
+		c.Actor = p.Subject
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		c.ClientIP = host
+	}
+	return c
+}
@@ -0,0 +1,58 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testEM/pkg/ratelimit"
+	"time"
+)
+
+// ParseRouteTimeouts reads deadlines of routes from comma separated
+// route=duration entries, e.g. "default=30s,POST /api/v1/songs/import=10m".
+// A route with timeout "none" has no deadline.
+func ParseRouteTimeouts(s string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, spec, ok := strings.Cut(entry, "=")
+		route = strings.Join(strings.Fields(route), " ")
+		spec = strings.TrimSpace(spec)
+		if !ok || route == "" {
+			return nil, fmt.Errorf("route timeout entry %q must be route=duration", entry)
+		}
+		if spec == "none" {
+			timeouts[route] = 0
+			continue
+		}
+		d, err := time.ParseDuration(spec)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("timeout of %s must be a positive duration", route)
+		}
+		timeouts[route] = d
+	}
+	return timeouts, nil
+}
+
+// deadline cancels the request context once the timeout of the route passes,
+// queries and upstream calls are cut short and the client gets 504.
+func (h *handler) deadline(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		timeout, ok := h.timeouts[routeKey(r)]
+		if !ok {
+			timeout = h.timeouts[ratelimit.DefaultRoute]
+		}
+		if timeout <= 0 {
+			next(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
@@ -1,21 +1,100 @@
 package delivery
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"testEM/internal/entities"
+	"testEM/internal/usecase"
 )
 
+const problemContentType = "application/problem+json"
+
+// HttpError is a problem details body as described in RFC 7807.
 type HttpError struct {
-	detail string
+	Type     string                    `json:"type"`
+	Title    string                    `json:"title"`
+	Status   int                       `json:"status"`
+	Code     string                    `json:"code"`
+	Detail   string                    `json:"detail,omitempty"`
+	Instance string                    `json:"instance,omitempty"`
+	Errors   []entities.FieldViolation `json:"errors,omitempty"`
 }
 
-func ReturnHttpError(w http.ResponseWriter, e error) {
+// NewHttpError maps domain errors to the status code and error code
+// returned to the client. Unknown errors are reported as internal
+// without exposing their text.
+func NewHttpError(r *http.Request, e error) *HttpError {
 	he := &HttpError{
-		detail: e.Error(),
+		Detail: e.Error(),
+	}
+	if r != nil {
+		he.Instance = r.URL.Path
+	}
+
+	var validationErr *entities.ValidationError
+	var upstreamErr *entities.UpstreamError
+	switch {
+	case deadlineExceeded(r):
+		he.Status, he.Code = http.StatusGatewayTimeout, "timeout"
+		he.Detail = "request took longer than allowed"
+	case errors.As(e, &validationErr):
+		he.Status, he.Code = http.StatusBadRequest, "validation_failed"
+		he.Errors = validationErr.Violations
+	case errors.Is(e, entities.ErrNotFound):
+		he.Status, he.Code = http.StatusNotFound, "not_found"
+	case errors.Is(e, entities.ErrConflict):
+		he.Status, he.Code = http.StatusConflict, "conflict"
+	case errors.Is(e, entities.ErrUnauthorized):
+		he.Status, he.Code = http.StatusUnauthorized, "unauthorized"
+	case errors.Is(e, entities.ErrForbidden):
+		he.Status, he.Code = http.StatusForbidden, "forbidden"
+	case errors.Is(e, entities.ErrTooManyRequests):
+		he.Status, he.Code = http.StatusTooManyRequests, "too_many_requests"
+	case errors.Is(e, entities.ErrPreconditionFailed):
+		he.Status, he.Code = http.StatusPreconditionFailed, "precondition_failed"
+	case errors.As(e, &upstreamErr):
+		switch {
+		case errors.Is(e, usecase.ErrUpstreamTimeout):
+			he.Status, he.Code = http.StatusGatewayTimeout, "upstream_timeout"
+		case errors.Is(e, usecase.ErrUpstreamUnavailable):
+			he.Status, he.Code = http.StatusServiceUnavailable, "upstream_unavailable"
+		case upstreamErr.StatusCode == http.StatusNotFound:
+			he.Status, he.Code = http.StatusNotFound, "upstream_not_found"
+		default:
+			he.Status, he.Code = http.StatusBadGateway, "upstream_failure"
+		}
+	default:
+		he.Status, he.Code = http.StatusInternalServerError, "internal_error"
+		he.Detail = "internal server error"
 	}
+
+	he.Type = "urn:testem:problem:" + he.Code
+	he.Title = http.StatusText(he.Status)
+	return he
+}
+
+func ReturnHttpError(w http.ResponseWriter, r *http.Request, e error) {
+	he := NewHttpError(r, e)
 	resp, err := json.Marshal(he)
 	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(he.Status)
 	w.Write(resp)
 }
+
+// deadlineExceeded reports whether the request ran out of time. Only the
+// request context is checked: drivers may report a cancelled query with an
+// error of their own, and upstream calls time out on deadlines of their own.
+func deadlineExceeded(r *http.Request) bool {
+	return r != nil && errors.Is(r.Context().Err(), context.DeadlineExceeded)
+}
+
+// badRequest wraps errors of reading the request into a validation error.
+func badRequest(field string, err error) error {
+	return entities.NewValidationError(field, err.Error())
+}
@@ -0,0 +1,67 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testEM/internal/entities"
+)
+
+// songETag is the strong entity tag of the song version.
+func songETag(s *entities.Song) string {
+	if s == nil || s.Version == nil {
+		return ""
+	}
+	return `"` + strconv.Itoa(*s.Version) + `"`
+}
+
+func setSongETag(w http.ResponseWriter, s *entities.Song) {
+	if etag := songETag(s); etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+}
+
+// ifMatch returns the song versions listed in If-Match, nil when any version
+// is accepted. Weak and unknown tags never match under strong comparison, a
+// header listing only them fails the precondition.
+func ifMatch(r *http.Request, songID string) ([]int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return nil, nil
+	}
+
+	var versions []int
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, nil
+		}
+		tag, ok := strings.CutPrefix(tag, `"`)
+		if ok {
+			tag, ok = strings.CutSuffix(tag, `"`)
+		}
+		if version, err := strconv.Atoi(tag); ok && err == nil {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, &entities.PreconditionFailedError{Resource: "song", ID: songID}
+	}
+	return versions, nil
+}
+
+// noneMatch reports whether If-None-Match lists the tag, weak tags are
+// compared by their value.
+func noneMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || etag == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
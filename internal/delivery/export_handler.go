@@ -0,0 +1,224 @@
+package delivery
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"testEM/internal/entities"
+	"testEM/internal/usecase"
+	"testEM/internal/validation"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// exportFlushEvery is the number of songs written between flushes of the response.
+const exportFlushEvery = 100
+
+var exportContentTypes = map[string]string{
+	entities.ExportFormatJSON:   "application/json",
+	entities.ExportFormatCSV:    "text/csv",
+	entities.ExportFormatNDJSON: "application/x-ndjson",
+}
+
+// exportFormat takes the format from the query, falling back to the first
+// supported media type of the Accept header and then to json.
+func exportFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if _, ok := exportContentTypes[format]; !ok {
+			return "", entities.NewValidationError("format", "must be one of json csv ndjson")
+		}
+		return format, nil
+	}
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(part))
+		switch mediaType {
+		case "application/json":
+			return entities.ExportFormatJSON, nil
+		case "text/csv":
+			return entities.ExportFormatCSV, nil
+		case "application/x-ndjson", "application/ndjson":
+			return entities.ExportFormatNDJSON, nil
+		}
+	}
+	return entities.ExportFormatJSON, nil
+}
+
+type songWriter interface {
+	begin() error
+	write(s *entities.ExportedSong) error
+	end() error
+}
+
+func newSongWriter(format string, w io.Writer) songWriter {
+	switch format {
+	case entities.ExportFormatCSV:
+		return &csvSongWriter{w: csv.NewWriter(w)}
+	case entities.ExportFormatNDJSON:
+		return &ndjsonSongWriter{enc: json.NewEncoder(w)}
+	default:
+		return &jsonSongWriter{w: w}
+	}
+}
+
+// jsonSongWriter writes songs as elements of a single json array.
+type jsonSongWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (jw *jsonSongWriter) begin() error {
+	_, err := io.WriteString(jw.w, "[")
+	return err
+}
+
+func (jw *jsonSongWriter) write(s *entities.ExportedSong) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if jw.written {
+		if _, err = io.WriteString(jw.w, ","); err != nil {
+			return err
+		}
+	}
+	jw.written = true
+	_, err = jw.w.Write(data)
+	return err
+}
+
+func (jw *jsonSongWriter) end() error {
+	_, err := io.WriteString(jw.w, "]\n")
+	return err
+}
+
+type ndjsonSongWriter struct {
+	enc *json.Encoder
+}
+
+func (nw *ndjsonSongWriter) begin() error {
+	return nil
+}
+
+func (nw *ndjsonSongWriter) write(s *entities.ExportedSong) error {
+	return nw.enc.Encode(s)
+}
+
+func (nw *ndjsonSongWriter) end() error {
+	return nil
+}
+
+// csvSongWriter writes the columns accepted by the import, verses are
+// joined into lyrics with blank lines.
+type csvSongWriter struct {
+	w *csv.Writer
+}
+
+func (cw *csvSongWriter) begin() error {
+	return cw.w.Write([]string{"id", "group", "song", "releaseDate", "link", "lyrics"})
+}
+
+func (cw *csvSongWriter) write(s *entities.ExportedSong) error {
+	value := func(v *string) string {
+		if v == nil {
+			return ""
+		}
+		return *v
+	}
+
+	releaseDate := ""
+	if s.ReleaseDate != nil {
+		releaseDate = s.ReleaseDate.Format(usecase.DateLayout)
+	}
+	verses := make([]string, 0, len(s.Verses))
+	for _, v := range s.Verses {
+		verses = append(verses, v.Content)
+	}
+
+	return cw.w.Write([]string{value(s.ID), value(s.Group), value(s.Song.Song), releaseDate, value(s.Link), strings.Join(verses, "\n\n")})
+}
+
+func (cw *csvSongWriter) end() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// @Summary      Export songs
+// @Description  stream all songs matching filters with their verses as json array, csv or ndjson.
+// @Description  Format is taken from format query or Accept header, accepts the same filters and sort as songs listing except lyrics, pagination is ignored.
+// @Produce      json
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Success      200  {array}  entities.ExportedSong
+// @Failure      400  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/export [get]
+func (h *handler) ExportSongs(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r)
+	if err != nil {
+		ReturnHttpError(w, r, err)
+		return
+	}
+
+	searchOptions := entities.SongSearchOptions{}
+	if err := validation.DecodeQuery(r.URL.Query(), &searchOptions); err != nil {
+		h.log.Error("Failed to read search options",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+
+	// the response is started with the first song, so errors happening
+	// before it can still be reported with a proper status
+	sw := newSongWriter(format, w)
+	flusher, _ := w.(http.Flusher)
+	started := false
+	start := func() error {
+		started = true
+		w.Header().Set("Content-Type", exportContentTypes[format])
+		w.Header().Set("Content-Disposition", `attachment; filename="songs.`+format+`"`)
+		w.WriteHeader(http.StatusOK)
+		return sw.begin()
+	}
+
+	written := 0
+	err = h.uc.ExportSongs(r.Context(), searchOptions, func(s *entities.ExportedSong) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := sw.write(s); err != nil {
+			return err
+		}
+		written++
+		if flusher != nil && written%exportFlushEvery == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = sw.end()
+	}
+	if err != nil {
+		h.log.Error("Failed to export songs",
+			zap.Int("written", written),
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		if !started {
+			ReturnHttpError(w, r, err)
+		}
+	}
+}
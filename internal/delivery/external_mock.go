@@ -1,11 +1,14 @@
 package delivery
 
-import "testEM/internal/entities"
+import (
+	"context"
+	"testEM/internal/entities"
+)
 
 type MockExternal struct {
 }
 
-func (m *MockExternal) GetSongDetails(track entities.AddSongDTO) (*entities.SongDetail, error) {
+func (m *MockExternal) GetSongDetails(ctx context.Context, track entities.AddSongDTO) (*entities.SongDetail, error) {
 	return &entities.SongDetail{
 		Link:        "https://www.youtube.com/watch?v=Xsp3_a-PMTw",
 		ReleaseDate: "16.07.2006",
@@ -0,0 +1,213 @@
+package delivery
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testEM/internal/entities"
+	"testEM/internal/validation"
+	"time"
+
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+// @Summary      Get groups
+// @Description  get groups, name filters by substring ignoring case
+// @Produce      json
+// @Success      200  {object} entities.GroupsWrapper
+// @Failure      400  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /groups [get]
+func (h *handler) GetGroups(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	searchOptions := entities.GroupSearchOptions{}
+	if err := validation.DecodeQuery(r.URL.Query(), &searchOptions); err != nil {
+		h.log.Error("Failed to read search options",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+
+	groups, err := h.uc.GetGroups(r.Context(), searchOptions)
+	if err != nil {
+		h.log.Error("Failed to get groups",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, groups)
+}
+
+// @Summary      Get group
+// @Description  get group with specified id
+// @Produce      json
+// @Success      200  {object} entities.Group
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /groups/{id} [get]
+func (h *handler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	groupID := chi.URLParam(r, "id")
+
+	g, err := h.uc.GetGroup(r.Context(), groupID)
+	if err != nil {
+		h.log.Error("Failed to get group",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, g)
+}
+
+// @Summary      Add group
+// @Description  add group, names differing only in case or spaces are considered equal
+// @Produce      json
+// @Success      201  {object} entities.Group
+// @Failure      400  {object} HttpError
+// @Failure      409  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /groups [post]
+func (h *handler) AddGroup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	dto := entities.GroupDTO{}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &dto)
+	}
+	if err != nil {
+		h.log.Error("Failed to read body",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("body", err))
+		return
+	}
+
+	g, err := h.uc.AddGroup(r.Context(), dto, caller(r))
+	if err != nil {
+		h.log.Error("Failed to add group",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusCreated, g)
+}
+
+// @Summary      Rename group
+// @Description  rename group with specified id, the new name is applied to all its songs
+// @Produce      json
+// @Success      200  {object} entities.Group
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      409  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /groups/{id} [patch]
+func (h *handler) RenameGroup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	groupID := chi.URLParam(r, "id")
+
+	dto := entities.GroupDTO{}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &dto)
+	}
+	if err != nil {
+		h.log.Error("Failed to read body",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("body", err))
+		return
+	}
+
+	g, err := h.uc.RenameGroup(r.Context(), groupID, dto, caller(r))
+	if err != nil {
+		h.log.Error("Failed to rename group",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, g)
+}
+
+// @Summary      Delete group
+// @Description  delete group with specified id, groups with songs can not be deleted
+// @Produce      json
+// @Success      204  {object} nil
+// @Failure      404  {object} HttpError
+// @Failure      409  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /groups/{id} [delete]
+func (h *handler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	groupID := chi.URLParam(r, "id")
+
+	err := h.uc.DeleteGroup(r.Context(), groupID, caller(r))
+	if err != nil {
+		h.log.Error("Failed to delete group",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Get group songs
+// @Description  get songs of group, accepts the same filters as songs listing
+// @Produce      json
+// @Success      200  {object} entities.SongsWrapper
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /groups/{id}/songs [get]
+func (h *handler) GetGroupSongs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	groupID := chi.URLParam(r, "id")
+
+	searchOptions := entities.SongSearchOptions{}
+	if err := validation.DecodeQuery(r.URL.Query(), &searchOptions); err != nil {
+		h.log.Error("Failed to read search options",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+
+	s, err := h.uc.GetGroupSongs(r.Context(), groupID, searchOptions)
+	if err != nil {
+		h.log.Error("Failed to get group songs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, s)
+}
@@ -2,15 +2,16 @@ package delivery
 
 import (
 	"encoding/json"
-	"errors"
 	"github.com/go-chi/chi"
 	"io"
 	"net/http"
 	"strconv"
+	"testEM/internal/auth"
 	"testEM/internal/entities"
-	"testEM/internal/repository"
 	"testEM/internal/usecase"
+	"testEM/internal/validation"
 	"testEM/pkg/middleware"
+	"testEM/pkg/ratelimit"
 	"time"
 
 	httpSwagger "github.com/swaggo/http-swagger/v2"
@@ -19,9 +20,40 @@ import (
 )
 
 const (
-	songsUrl  = "/api/v1/songs"
-	songUrl   = "/api/v1/songs/{id}"
-	versesUrl = "/api/v1/songs/{id}/verses"
+	songsUrl     = "/api/v1/songs"
+	songUrl      = "/api/v1/songs/{id}"
+	importUrl    = "/api/v1/songs/import"
+	exportUrl    = "/api/v1/songs/export"
+	versesUrl    = "/api/v1/songs/{id}/verses"
+	lyricsUrl    = "/api/v1/songs/{id}/lyrics"
+	verseUrl     = "/api/v1/songs/{id}/verses/{num}"
+	moveUrl      = "/api/v1/songs/{id}/verses/{num}/move"
+	timingUrl    = "/api/v1/songs/{id}/timing"
+	activeUrl    = "/api/v1/songs/{id}/lines/active"
+	structureUrl = "/api/v1/songs/{id}/structure"
+	historyUrl   = "/api/v1/songs/{id}/history"
+	revisionUrl  = "/api/v1/songs/{id}/history/{num}"
+	rollbackUrl  = "/api/v1/songs/{id}/history/{num}/rollback"
+	restoreUrl   = "/api/v1/songs/{id}/restore"
+
+	deletedSongsUrl = "/api/v1/admin/songs/deleted"
+	auditUrl        = "/api/v1/audit"
+
+	groupsUrl     = "/api/v1/groups"
+	groupUrl      = "/api/v1/groups/{id}"
+	groupSongsUrl = "/api/v1/groups/{id}/songs"
+
+	albumsUrl    = "/api/v1/albums"
+	albumUrl     = "/api/v1/albums/{id}"
+	tracksUrl    = "/api/v1/albums/{id}/tracks"
+	trackUrl     = "/api/v1/albums/{id}/tracks/{songId}"
+	trackMoveUrl = "/api/v1/albums/{id}/tracks/{songId}/move"
+
+	playlistsUrl        = "/api/v1/playlists"
+	playlistUrl         = "/api/v1/playlists/{id}"
+	playlistSongsUrl    = "/api/v1/playlists/{id}/songs"
+	playlistSongUrl     = "/api/v1/playlists/{id}/songs/{num}"
+	playlistSongMoveUrl = "/api/v1/playlists/{id}/songs/{num}/move"
 )
 
 type Handler interface {
@@ -29,24 +61,81 @@ type Handler interface {
 }
 
 type handler struct {
-	log *zap.Logger
-	uc  *usecase.Usecase
+	log      *zap.Logger
+	uc       *usecase.Usecase
+	importer *usecase.Importer
+	auth     *auth.Authenticator
+	limiter  *ratelimit.Limiter
+	timeouts map[string]time.Duration
 }
 
-func NewHandler(lg *zap.Logger, uc *usecase.Usecase) Handler {
+// NewHandler takes deadlines of routes keyed like "GET /api/v1/songs", the
+// "default" one applies to routes without their own.
+func NewHandler(lg *zap.Logger, uc *usecase.Usecase, importer *usecase.Importer, authenticator *auth.Authenticator, limiter *ratelimit.Limiter, timeouts map[string]time.Duration) Handler {
 	return &handler{
-		log: lg,
-		uc:  uc,
+		log:      lg,
+		uc:       uc,
+		importer: importer,
+		auth:     authenticator,
+		limiter:  limiter,
+		timeouts: timeouts,
 	}
 }
 
 func (h *handler) ApplyRoutes(o *middleware.Onion) *chi.Mux {
+	// every request is limited by IP before authentication, so requests with
+	// bad credentials count too, and by subject after it, so a made up API key
+	// or token can't pick another client's bucket
+	reader := o.With(h.rateLimit, h.require(auth.RoleReader), h.rateLimitIP, h.deadline)
+	editor := o.With(h.rateLimit, h.require(auth.RoleEditor), h.rateLimitIP, h.deadline)
+	admin := o.With(h.rateLimit, h.require(auth.RoleAdmin), h.rateLimitIP, h.deadline)
+
 	router := chi.NewRouter()
-	router.Get(songsUrl, o.Apply(h.GetSongs))
-	router.Get(versesUrl, o.Apply(h.GetVersesBySongID))
-	router.Delete(songUrl, o.Apply(h.DeleteSong))
-	router.Patch(songUrl, o.Apply(h.PatchSong))
-	router.Post(songsUrl, o.Apply(h.AddSong))
+	router.Get(songsUrl, reader.Apply(h.GetSongs))
+	router.Get(exportUrl, reader.Apply(h.ExportSongs))
+	router.Get(versesUrl, reader.Apply(h.GetVersesBySongID))
+	router.Get(lyricsUrl, reader.Apply(h.GetLyrics))
+	router.Get(structureUrl, reader.Apply(h.GetStructure))
+	router.Get(historyUrl, reader.Apply(h.GetHistory))
+	router.Get(revisionUrl, reader.Apply(h.GetRevision))
+	router.Post(rollbackUrl, editor.Apply(h.RollbackSong))
+	router.Post(versesUrl, editor.Apply(h.InsertVerse))
+	router.Get(verseUrl, reader.Apply(h.GetVerse))
+	router.Put(verseUrl, editor.Apply(h.ReplaceVerse))
+	router.Delete(verseUrl, editor.Apply(h.DeleteVerse))
+	router.Post(moveUrl, editor.Apply(h.MoveVerse))
+	router.Put(timingUrl, editor.Apply(h.SyncLyrics))
+	router.Get(activeUrl, reader.Apply(h.GetActiveLine))
+	router.Get(songUrl, reader.Apply(h.GetSong))
+	router.Delete(songUrl, editor.Apply(h.DeleteSong))
+	router.Post(restoreUrl, editor.Apply(h.RestoreSong))
+	router.Get(deletedSongsUrl, admin.Apply(h.GetDeletedSongs))
+	router.Get(auditUrl, admin.Apply(h.GetAudit))
+	router.Patch(songUrl, editor.Apply(h.PatchSong))
+	router.Post(songsUrl, editor.Apply(h.AddSong))
+	router.Post(importUrl, admin.Apply(h.ImportSongs))
+	router.Get(groupsUrl, reader.Apply(h.GetGroups))
+	router.Post(groupsUrl, editor.Apply(h.AddGroup))
+	router.Get(groupUrl, reader.Apply(h.GetGroup))
+	router.Patch(groupUrl, editor.Apply(h.RenameGroup))
+	router.Delete(groupUrl, admin.Apply(h.DeleteGroup))
+	router.Get(groupSongsUrl, reader.Apply(h.GetGroupSongs))
+	router.Get(albumsUrl, reader.Apply(h.GetAlbums))
+	router.Post(albumsUrl, editor.Apply(h.AddAlbum))
+	router.Get(albumUrl, reader.Apply(h.GetAlbum))
+	router.Patch(albumUrl, editor.Apply(h.PatchAlbum))
+	router.Delete(albumUrl, editor.Apply(h.DeleteAlbum))
+	router.Post(tracksUrl, editor.Apply(h.AddTrack))
+	router.Delete(trackUrl, editor.Apply(h.RemoveTrack))
+	router.Post(trackMoveUrl, editor.Apply(h.MoveTrack))
+	router.Get(playlistsUrl, reader.Apply(h.GetPlaylists))
+	router.Post(playlistsUrl, editor.Apply(h.AddPlaylist))
+	router.Get(playlistUrl, reader.Apply(h.GetPlaylist))
+	router.Patch(playlistUrl, editor.Apply(h.PatchPlaylist))
+	router.Delete(playlistUrl, editor.Apply(h.DeletePlaylist))
+	router.Post(playlistSongsUrl, editor.Apply(h.AddPlaylistSong))
+	router.Delete(playlistSongUrl, editor.Apply(h.RemovePlaylistSong))
+	router.Post(playlistSongMoveUrl, editor.Apply(h.MovePlaylistSong))
 
 	router.Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL("http://localhost:3333/swagger/doc.json"),
@@ -55,87 +144,35 @@ func (h *handler) ApplyRoutes(o *middleware.Onion) *chi.Mux {
 }
 
 // @Summary      Get songs
-// @Description  get string by filters
+// @Description  get string by filters, lyrics search ranks songs by matching verses
 // @Produce      json
 // @Success      200  {object}  entities.SongsWrapper
 // @Failure      400  {object} HttpError
 // @Failure      404  {object} HttpError
 // @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /songs [get]
 func (h *handler) GetSongs(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	searchOptions := entities.SongSearchOptions{}
-
-	params := r.URL.Query()
-	if params != nil && len(params) > 0 {
-		if val := params.Get("song"); val != "" {
-			searchOptions.Song = &val
-		}
-		if val := params.Get("group"); val != "" {
-			searchOptions.Group = &val
-		}
-		if val := params.Get("page"); val != "" {
-			page, err := strconv.Atoi(val)
-			if err != nil {
-				h.log.Debug("Failed to parse page int from string",
-					zap.String("message", err.Error()),
-					zap.Time("time", time.Now()),
-				)
-			}
-			searchOptions.Page = &page
-		}
-		if val := params.Get("perPage"); val != "" {
-			perpage, err := strconv.Atoi(val)
-			if err != nil {
-				h.log.Debug("Failed to parse page int from string",
-					zap.String("message", err.Error()),
-					zap.Time("time", time.Now()),
-				)
-			}
-			searchOptions.PerPage = &perpage
-		}
-
-		if val := params.Get("releaseDateAfter"); val != "" {
-			d, err := time.Parse(usecase.DateLayout, val)
-			if err != nil {
-				h.log.Debug("Failed to parse release date",
-					zap.String("message", err.Error()),
-					zap.Time("time", time.Now()),
-				)
-			}
-			searchOptions.ReleaseDateAfter = &d
-		}
-		if val := params.Get("releaseDateBefore"); val != "" {
-			d, err := time.Parse(usecase.DateLayout, val)
-			if err != nil {
-				h.log.Debug("Failed to parse release date",
-					zap.String("message", err.Error()),
-					zap.Time("time", time.Now()),
-				)
-			}
-			searchOptions.ReleaseDateBefore = &d
-		}
+	if err := validation.DecodeQuery(r.URL.Query(), &searchOptions); err != nil {
+		h.log.Error("Failed to read search options",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
 	}
 
-	s, err := h.uc.GetSongsWithFilters(searchOptions)
+	s, err := h.uc.GetSongsWithFilters(r.Context(), searchOptions)
 	if err != nil {
-		if errors.Is(err, &repository.NotFoundErr{}) {
-			h.log.Error("Failed get songs with filters: not found",
-				zap.String("message", err.Error()),
-				zap.Time("time", time.Now()),
-			)
-			w.WriteHeader(http.StatusNotFound)
-			ReturnHttpError(w, err)
-			return
-		} else {
-			h.log.Error("Failed get songs with filters",
-				zap.String("message", err.Error()),
-				zap.Time("time", time.Now()),
-			)
-			w.WriteHeader(http.StatusInternalServerError)
-			ReturnHttpError(w, err)
-			return
-		}
+		h.log.Error("Failed get songs with filters",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
 	}
 
 	resp, err := json.Marshal(s)
@@ -144,8 +181,7 @@ func (h *handler) GetSongs(w http.ResponseWriter, r *http.Request) {
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		w.WriteHeader(http.StatusInternalServerError)
-		ReturnHttpError(w, err)
+		ReturnHttpError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -153,52 +189,52 @@ func (h *handler) GetSongs(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary      Get verses
-// @Description  get verses for song
+// @Description  get verses for song. Format is taken from format query or Accept header: json page of verses,
+// @Description  or whole text as plain text, html with verse and line markup or lrc when timing data is available
 // @Produce      json
+// @Produce      plain
+// @Produce      html
+// @Produce      application/x-lrc
 // @Success      200  {object}  entities.VersesWrapper
 // @Failure      400  {object} HttpError
 // @Failure      404  {object} HttpError
 // @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /songs/{id}/verses [get]
 func (h *handler) GetVersesBySongID(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
+	w.Header().Set("Vary", "Accept")
 	songID := chi.URLParam(r, "id")
+	format, err := lyricsFormat(r)
+	if err != nil {
+		ReturnHttpError(w, r, err)
+		return
+	}
+	if format != lyricsFormatJSON {
+		h.writeLyrics(w, r, songID, format)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	searchOptions := entities.VerseSearchOptions{}
 	searchOptions.SongID = &songID
 
-	params := r.URL.Query()
-	if params != nil && len(params) > 0 {
-		if val := params.Get("page"); val != "" {
-			page, err := strconv.Atoi(val)
-			if err != nil {
-				h.log.Debug("Failed to parse page int from string",
-					zap.String("message", err.Error()),
-					zap.Time("time", time.Now()),
-				)
-			}
-			searchOptions.Page = &page
-		}
-		if val := params.Get("perPage"); val != "" {
-			perpage, err := strconv.Atoi(val)
-			if err != nil {
-				h.log.Debug("Failed to parse page int from string",
-					zap.String("message", err.Error()),
-					zap.Time("time", time.Now()),
-				)
-			}
-			searchOptions.PerPage = &perpage
-		}
+	if err := validation.DecodeQuery(r.URL.Query(), &searchOptions); err != nil {
+		h.log.Error("Failed to read search options",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
 	}
 
-	s, err := h.uc.GetVerses(searchOptions)
+	s, err := h.uc.GetVerses(r.Context(), searchOptions)
 	if err != nil {
 		h.log.Error("Failed get song text",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		w.WriteHeader(http.StatusNotFound)
-		ReturnHttpError(w, err)
+		ReturnHttpError(w, r, err)
 		return
 	}
 	resp, err := json.Marshal(s)
@@ -207,45 +243,147 @@ func (h *handler) GetVersesBySongID(w http.ResponseWriter, r *http.Request) {
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		w.WriteHeader(http.StatusInternalServerError)
-		ReturnHttpError(w, err)
+		ReturnHttpError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 	w.Write(resp)
 }
 
+// @Summary      Get song
+// @Description  get song with specified id, its version is returned as ETag. With If-None-Match listing it 304 is returned.
+// @Produce      json
+// @Param        If-None-Match  header  string  false  "ETags of the song"
+// @Success      200  {object} entities.Song
+// @Success      304  {object} nil
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/{id} [get]
+func (h *handler) GetSong(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	song, err := h.uc.GetSong(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.log.Error("Failed get song",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+
+	setSongETag(w, song)
+	if noneMatch(r, songETag(song)) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, song)
+}
+
 // @Summary      Delete song
-// @Description  delete song with specified id
+// @Description  delete song with specified id, it can be restored until it is purged after the retention.
+// @Description  With If-Match the song is only deleted at one of the versions from the listed ETags.
 // @Produce      json
+// @Param        If-Match  header  string  false  "ETag of the song"
 // @Success      200  {object} nil
 // @Failure      400  {object} HttpError
 // @Failure      404  {object} HttpError
+// @Failure      412  {object} HttpError
 // @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /songs [delete]
 func (h *handler) DeleteSong(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	songID := chi.URLParam(r, "id")
-	err := h.uc.DeleteSong(songID)
+	versions, err := ifMatch(r, songID)
+	if err != nil {
+		ReturnHttpError(w, r, err)
+		return
+	}
+
+	err = h.uc.DeleteSong(r.Context(), songID, versions, caller(r))
 	if err != nil {
 		h.log.Error("Failed delete song",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		w.WriteHeader(http.StatusInternalServerError)
-		ReturnHttpError(w, err)
+		ReturnHttpError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// @Summary      Restore song
+// @Description  restore deleted song with its verses
+// @Produce      json
+// @Success      200  {object} entities.Song
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/{id}/restore [post]
+func (h *handler) RestoreSong(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	s, err := h.uc.RestoreSong(r.Context(), chi.URLParam(r, "id"), caller(r))
+	if err != nil {
+		h.log.Error("Failed restore song",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	setSongETag(w, s)
+	h.writeJSON(w, r, http.StatusOK, s)
+}
+
+// @Summary      Get deleted songs
+// @Description  get deleted songs that are not purged yet, most recently deleted first
+// @Produce      json
+// @Success      200  {object} entities.SongsWrapper
+// @Failure      400  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /admin/songs/deleted [get]
+func (h *handler) GetDeletedSongs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	searchOptions := entities.DeletedSongSearchOptions{}
+	if err := validation.DecodeQuery(r.URL.Query(), &searchOptions); err != nil {
+		h.log.Error("Failed to read search options",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+
+	s, err := h.uc.GetDeletedSongs(r.Context(), searchOptions)
+	if err != nil {
+		h.log.Error("Failed get deleted songs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, s)
+}
+
 // @Summary      Patch song
-// @Description  update song with specified id
+// @Description  update song with specified id, with If-Match the song is only updated at one of the versions from the listed ETags
 // @Produce      json
+// @Param        If-Match  header  string  false  "ETag of the song"
 // @Success      200  {object} entities.Song
 // @Failure      400  {object} HttpError
 // @Failure      404  {object} HttpError
+// @Failure      409  {object} HttpError
+// @Failure      412  {object} HttpError
 // @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /songs [patch]
 func (h *handler) PatchSong(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -259,42 +397,52 @@ func (h *handler) PatchSong(w http.ResponseWriter, r *http.Request) {
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		w.WriteHeader(http.StatusBadRequest)
-		ReturnHttpError(w, err)
+		ReturnHttpError(w, r, badRequest("body", err))
 		return
 	}
 
-	song, err := h.uc.PatchSong(songID, patchDTO)
+	versions, err := ifMatch(r, songID)
+	if err != nil {
+		ReturnHttpError(w, r, err)
+		return
+	}
+
+	song, err := h.uc.PatchSong(r.Context(), songID, patchDTO, versions, caller(r))
 	if err != nil {
 		h.log.Error("Failed to update song",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		w.WriteHeader(http.StatusNotFound)
-		ReturnHttpError(w, err)
+		ReturnHttpError(w, r, err)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
+	setSongETag(w, song)
 	resp, err := json.Marshal(song)
 	if err != nil {
 		h.log.Debug("Failed to serialize response",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		w.WriteHeader(http.StatusInternalServerError)
-		ReturnHttpError(w, err)
+		ReturnHttpError(w, r, err)
 		return
 	}
+	w.WriteHeader(http.StatusOK)
 	w.Write(resp)
 }
 
 // @Summary      Add song
-// @Description  add song
+// @Description  add song, with async=true the song is stored as pending and enriched in background
 // @Produce      json
-// @Success      200  {object} entities.Song
+// @Success      201  {object} entities.Song
+// @Success      202  {object} entities.Song
 // @Failure      400  {object} HttpError
 // @Failure      404  {object} HttpError
 // @Failure      500  {object} HttpError
+// @Failure      502  {object} HttpError
+// @Failure      503  {object} HttpError
+// @Failure      504  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /songs [post]
 func (h *handler) AddSong(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -307,31 +455,50 @@ func (h *handler) AddSong(w http.ResponseWriter, r *http.Request) {
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		w.WriteHeader(http.StatusBadRequest)
-		ReturnHttpError(w, err)
+		ReturnHttpError(w, r, badRequest("body", err))
 		return
 	}
 
-	song, err := h.uc.AddSong(songDTO)
+	status := http.StatusCreated
+	var song *entities.Song
+	if async, _ := strconv.ParseBool(r.URL.Query().Get("async")); async {
+		status = http.StatusAccepted
+		song, err = h.uc.AddSongAsync(r.Context(), songDTO, caller(r))
+	} else {
+		song, err = h.uc.AddSong(r.Context(), songDTO, caller(r))
+	}
 	if err != nil {
 		h.log.Error("Failed to add song",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		w.WriteHeader(http.StatusInternalServerError)
-		ReturnHttpError(w, err)
+		ReturnHttpError(w, r, err)
 		return
 	}
-	w.WriteHeader(http.StatusCreated)
+	setSongETag(w, song)
 	resp, err := json.Marshal(song)
 	if err != nil {
 		h.log.Debug("Failed to serialize response",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		w.WriteHeader(http.StatusInternalServerError)
-		ReturnHttpError(w, err)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(resp)
+}
+
+func (h *handler) writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		h.log.Debug("Failed to serialize response",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
 		return
 	}
+	w.WriteHeader(status)
 	w.Write(resp)
 }
@@ -0,0 +1,59 @@
+package delivery
+
+import (
+	"mime"
+	"net/http"
+	"testEM/internal/entities"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxImportSize limits the size of import bodies.
+const maxImportSize = 64 << 20
+
+// importFormat takes the format from the query, falling back to the content type of the body.
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return entities.ImportFormatCSV
+	case "application/x-ndjson", "application/ndjson":
+		return entities.ImportFormatNDJSON
+	}
+	return ""
+}
+
+// @Summary      Import songs
+// @Description  add songs in bulk from csv with header or ndjson, format is taken from format query or content type.
+// @Description  Records contain group and song, optionally releaseDate, link and lyrics; missing ones are fetched from external API.
+// @Description  Lyrics are split into verses with splitter from query: blank, crlf or sections, configured one by default.
+// @Description  Body is limited to 64 MiB. Responds with outcome of every row.
+// @Accept       text/csv
+// @Accept       application/x-ndjson
+// @Produce      json
+// @Success      200  {object} entities.ImportReport
+// @Failure      400  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/import [post]
+func (h *handler) ImportSongs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	report, err := h.importer.Import(r.Context(), body, importFormat(r), r.URL.Query().Get("splitter"), caller(r))
+	if err != nil {
+		h.log.Error("Failed to import songs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, report)
+}
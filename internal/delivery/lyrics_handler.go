@@ -0,0 +1,173 @@
+package delivery
+
+import (
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"testEM/internal/entities"
+	"time"
+
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+const (
+	lyricsFormatJSON  = "json"
+	lyricsFormatPlain = "text"
+	lyricsFormatHTML  = "html"
+	lyricsFormatLRC   = "lrc"
+)
+
+var lyricsContentTypes = map[string]string{
+	lyricsFormatJSON:  "application/json",
+	lyricsFormatPlain: "text/plain; charset=utf-8",
+	lyricsFormatHTML:  "text/html; charset=utf-8",
+	lyricsFormatLRC:   "application/x-lrc; charset=utf-8",
+}
+
+// lyricsFormat takes the format from the query, falling back to the first
+// supported media type of the Accept header and then to json.
+func lyricsFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if _, ok := lyricsContentTypes[format]; !ok {
+			return "", entities.NewValidationError("format", "must be one of json text html lrc")
+		}
+		return format, nil
+	}
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(part))
+		switch mediaType {
+		case "application/json":
+			return lyricsFormatJSON, nil
+		case "text/plain":
+			return lyricsFormatPlain, nil
+		case "text/html":
+			return lyricsFormatHTML, nil
+		case "application/x-lrc", "text/x-lrc":
+			return lyricsFormatLRC, nil
+		}
+	}
+	return lyricsFormatJSON, nil
+}
+
+// renderPlain restores the original text, verses are joined with blank lines
+// the same way they are split when the song is added.
+func renderPlain(lyrics *entities.Lyrics) string {
+	verses := make([]string, 0, len(lyrics.Verses))
+	for _, v := range lyrics.Verses {
+		verses = append(verses, v.Content)
+	}
+	return strings.Join(verses, "\n\n")
+}
+
+// renderHTML returns a fragment with a paragraph per verse and a span per line.
+func renderHTML(lyrics *entities.Lyrics) string {
+	var b strings.Builder
+	b.WriteString(`<div class="lyrics">`)
+	for _, v := range lyrics.Verses {
+		b.WriteString(`<p class="verse" data-num="` + strconv.Itoa(v.Number) + `">`)
+		for i, line := range strings.Split(strings.ReplaceAll(v.Content, "\r\n", "\n"), "\n") {
+			if i > 0 {
+				b.WriteString("<br>")
+			}
+			b.WriteString(`<span class="line">` + html.EscapeString(line) + `</span>`)
+		}
+		b.WriteString("</p>")
+	}
+	b.WriteString("</div>\n")
+	return b.String()
+}
+
+// lrcTimestamp formats the offset as [mm:ss.xx].
+func lrcTimestamp(ms int) string {
+	return fmt.Sprintf("[%02d:%02d.%02d]", ms/60000, ms/1000%60, ms%1000/10)
+}
+
+// renderLRC writes timed lines with a blank line between verses, a line
+// ending before the next one starts is followed by an empty timed line.
+// Returns false when no line has timing.
+func renderLRC(lyrics *entities.Lyrics) (string, bool) {
+	var b strings.Builder
+	if lyrics.Song.Group != nil {
+		b.WriteString("[ar:" + lrcTagValue(*lyrics.Song.Group) + "]\n")
+	}
+	if lyrics.Song.Song != nil {
+		b.WriteString("[ti:" + lrcTagValue(*lyrics.Song.Song) + "]\n")
+	}
+
+	var timed []*entities.Line
+	for _, v := range lyrics.Verses {
+		for _, l := range v.Lines {
+			if l.StartMs != nil {
+				timed = append(timed, l)
+			}
+		}
+	}
+	if len(timed) == 0 {
+		return "", false
+	}
+
+	for i, l := range timed {
+		if i > 0 && timed[i-1].VerseNumber != l.VerseNumber {
+			b.WriteString("\n")
+		}
+		b.WriteString(lrcTimestamp(*l.StartMs) + l.Content + "\n")
+		if l.EndMs != nil && (i+1 == len(timed) || *timed[i+1].StartMs > *l.EndMs) {
+			b.WriteString(lrcTimestamp(*l.EndMs) + "\n")
+		}
+	}
+	return b.String(), true
+}
+
+// lrcTagValue drops brackets and line breaks, either would end the metadata tag early.
+func lrcTagValue(s string) string {
+	return strings.Join(strings.Fields(strings.NewReplacer("[", "", "]", "").Replace(s)), " ")
+}
+
+// writeLyrics renders the whole text of the song in one of the text formats.
+func (h *handler) writeLyrics(w http.ResponseWriter, r *http.Request, songID string, format string) {
+	lyrics, err := h.uc.GetLyrics(r.Context(), songID)
+	if err != nil {
+		h.log.Error("Failed to get song lyrics",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+
+	var body string
+	switch format {
+	case lyricsFormatHTML:
+		body = renderHTML(lyrics)
+	case lyricsFormatLRC:
+		var ok bool
+		if body, ok = renderLRC(lyrics); !ok {
+			ReturnHttpError(w, r, &entities.NotFoundError{Resource: "timing data of song", ID: songID})
+			return
+		}
+	default:
+		body = renderPlain(lyrics)
+	}
+
+	w.Header().Set("Content-Type", lyricsContentTypes[format])
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(body))
+}
+
+// @Summary      Get lyrics
+// @Description  get whole text of song as plain text, verses are separated with blank lines
+// @Produce      plain
+// @Success      200  {string} string
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/{id}/lyrics [get]
+func (h *handler) GetLyrics(w http.ResponseWriter, r *http.Request) {
+	h.writeLyrics(w, r, chi.URLParam(r, "id"), lyricsFormatPlain)
+}
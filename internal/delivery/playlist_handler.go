@@ -0,0 +1,299 @@
+package delivery
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"testEM/internal/entities"
+	"testEM/internal/validation"
+	"time"
+
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+// @Summary      Get playlists
+// @Description  get playlists, name filters by substring ignoring case
+// @Produce      json
+// @Success      200  {object} entities.PlaylistsWrapper
+// @Failure      400  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /playlists [get]
+func (h *handler) GetPlaylists(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	searchOptions := entities.PlaylistSearchOptions{}
+	if err := validation.DecodeQuery(r.URL.Query(), &searchOptions); err != nil {
+		h.log.Error("Failed to read search options",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+
+	playlists, err := h.uc.GetPlaylists(r.Context(), searchOptions)
+	if err != nil {
+		h.log.Error("Failed to get playlists",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, playlists)
+}
+
+// @Summary      Get playlist
+// @Description  get playlist with specified id and its songs in order
+// @Produce      json
+// @Success      200  {object} entities.Playlist
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /playlists/{id} [get]
+func (h *handler) GetPlaylist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	playlistID := chi.URLParam(r, "id")
+
+	p, err := h.uc.GetPlaylist(r.Context(), playlistID)
+	if err != nil {
+		h.log.Error("Failed to get playlist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, p)
+}
+
+// @Summary      Add playlist
+// @Description  create empty playlist
+// @Produce      json
+// @Success      201  {object} entities.Playlist
+// @Failure      400  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /playlists [post]
+func (h *handler) AddPlaylist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	dto := entities.AddPlaylistDTO{}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &dto)
+	}
+	if err != nil {
+		h.log.Error("Failed to read body",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("body", err))
+		return
+	}
+
+	p, err := h.uc.AddPlaylist(r.Context(), dto, caller(r))
+	if err != nil {
+		h.log.Error("Failed to add playlist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusCreated, p)
+}
+
+// @Summary      Patch playlist
+// @Description  update name or description of playlist with specified id
+// @Produce      json
+// @Success      200  {object} entities.Playlist
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /playlists/{id} [patch]
+func (h *handler) PatchPlaylist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	playlistID := chi.URLParam(r, "id")
+
+	dto := entities.PatchPlaylistDTO{}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &dto)
+	}
+	if err != nil {
+		h.log.Error("Failed to read body",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("body", err))
+		return
+	}
+
+	p, err := h.uc.PatchPlaylist(r.Context(), playlistID, dto, caller(r))
+	if err != nil {
+		h.log.Error("Failed to update playlist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, p)
+}
+
+// @Summary      Delete playlist
+// @Description  delete playlist with specified id, its songs are kept
+// @Produce      json
+// @Success      204  {object} nil
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /playlists/{id} [delete]
+func (h *handler) DeletePlaylist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	playlistID := chi.URLParam(r, "id")
+
+	err := h.uc.DeletePlaylist(r.Context(), playlistID, caller(r))
+	if err != nil {
+		h.log.Error("Failed to delete playlist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Add song to playlist
+// @Description  insert song at specified position, following entries are shifted down; appends when position is omitted
+// @Produce      json
+// @Success      201  {object} entities.Playlist
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /playlists/{id}/songs [post]
+func (h *handler) AddPlaylistSong(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	playlistID := chi.URLParam(r, "id")
+
+	dto := entities.AddPlaylistSongDTO{}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &dto)
+	}
+	if err != nil {
+		h.log.Error("Failed to read body",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("body", err))
+		return
+	}
+
+	p, err := h.uc.AddPlaylistSong(r.Context(), playlistID, dto, caller(r))
+	if err != nil {
+		h.log.Error("Failed to add song to playlist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusCreated, p)
+}
+
+// @Summary      Remove song from playlist
+// @Description  remove entry at specified position, following entries are shifted up
+// @Produce      json
+// @Success      204  {object} nil
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /playlists/{id}/songs/{num} [delete]
+func (h *handler) RemovePlaylistSong(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	playlistID := chi.URLParam(r, "id")
+	num, err := strconv.Atoi(chi.URLParam(r, "num"))
+	if err != nil {
+		h.log.Error("Failed to parse playlist position",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("num", err))
+		return
+	}
+
+	err = h.uc.RemovePlaylistSong(r.Context(), playlistID, num, caller(r))
+	if err != nil {
+		h.log.Error("Failed to remove song from playlist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Move song in playlist
+// @Description  move entry to another position, entries in between are renumbered
+// @Produce      json
+// @Success      200  {object} entities.Playlist
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /playlists/{id}/songs/{num}/move [post]
+func (h *handler) MovePlaylistSong(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	playlistID := chi.URLParam(r, "id")
+	num, err := strconv.Atoi(chi.URLParam(r, "num"))
+	if err != nil {
+		h.log.Error("Failed to parse playlist position",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("num", err))
+		return
+	}
+
+	dto := entities.MovePlaylistSongDTO{}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &dto)
+	}
+	if err != nil {
+		h.log.Error("Failed to read body",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("body", err))
+		return
+	}
+
+	p, err := h.uc.MovePlaylistSong(r.Context(), playlistID, num, dto, caller(r))
+	if err != nil {
+		h.log.Error("Failed to move song in playlist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, p)
+}
@@ -0,0 +1,107 @@
+package delivery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"testEM/internal/auth"
+	"testEM/internal/entities"
+	"time"
+
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+// rateLimitIP takes a token of the client IP for the route before the request
+// is authenticated, so that guessing credentials is limited too.
+func (h *handler) rateLimitIP(next http.HandlerFunc) http.HandlerFunc {
+	return h.limit(next, ipKey)
+}
+
+// rateLimit takes a token of the authenticated client for the route, it runs
+// after require so that only verified credentials pick the bucket.
+func (h *handler) rateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return h.limit(next, principalKey)
+}
+
+// limit takes a token of the client named by key for the route, requests
+// over the limit are rejected with 429. Limits are reported in RateLimit
+// headers. Requests without a key are let through.
+func (h *handler) limit(next http.HandlerFunc, key func(r *http.Request) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := key(r)
+		if client == "" {
+			next(w, r)
+			return
+		}
+
+		res, err := h.limiter.Take(r.Context(), routeKey(r), client)
+		if err != nil {
+			// the store being down must not take the API down with it
+			h.log.Error("Failed to check rate limit",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			next(w, r)
+			return
+		}
+		if res == nil {
+			next(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit.Burst))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", res.Limit.Burst, ceilSeconds(res.Limit.Window())))
+		if !res.Allowed {
+			h.log.Info("Request rate limited",
+				zap.String("route", routeKey(r)),
+				zap.Time("time", time.Now()),
+			)
+			retryAfter := max(ceilSeconds(res.RetryAfter), 1)
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			ReturnHttpError(w, r, &entities.TooManyRequestsError{RetryAfter: time.Duration(retryAfter) * time.Second})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// routeKey names the route as it is configured in limits, e.g. "GET /api/v1/songs/{id}".
+func routeKey(r *http.Request) string {
+	pattern := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		pattern = rctx.RoutePattern()
+	}
+	return r.Method + " " + pattern
+}
+
+// principalKey identifies the client by the subject it was authenticated as,
+// anonymous requests are only limited by IP. Subjects are hashed to keep
+// store keys short.
+func principalKey(r *http.Request) string {
+	p, ok := auth.PrincipalFrom(r.Context())
+	if !ok || p.Method == auth.MethodAnonymous {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(p.Subject))
+	return "sub:" + hex.EncodeToString(sum[:16])
+}
+
+// ipKey identifies the client by the IP it connects from.
+func ipKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
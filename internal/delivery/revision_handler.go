@@ -0,0 +1,110 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+	"testEM/internal/entities"
+	"testEM/internal/validation"
+	"time"
+
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+// @Summary      Get song history
+// @Description  get revisions of song newest first with changed fields, history is kept after song is deleted.
+// @Description  Changes are attributed to the authenticated subject.
+// @Produce      json
+// @Success      200  {object} entities.RevisionsWrapper
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/{id}/history [get]
+func (h *handler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	songID := chi.URLParam(r, "id")
+	searchOptions := entities.RevisionSearchOptions{SongID: &songID}
+	if err := validation.DecodeQuery(r.URL.Query(), &searchOptions); err != nil {
+		h.log.Error("Failed to read search options",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+
+	history, err := h.uc.GetHistory(r.Context(), searchOptions)
+	if err != nil {
+		h.log.Error("Failed to get song history",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, history)
+}
+
+// @Summary      Get song revision
+// @Description  get revision of song with the song and its verses as they were after it, snapshot is empty for deletion
+// @Produce      json
+// @Success      200  {object} entities.Revision
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/{id}/history/{num} [get]
+func (h *handler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	num, err := strconv.Atoi(chi.URLParam(r, "num"))
+	if err != nil {
+		ReturnHttpError(w, r, badRequest("num", err))
+		return
+	}
+
+	rev, err := h.uc.GetRevision(r.Context(), chi.URLParam(r, "id"), num)
+	if err != nil {
+		h.log.Error("Failed to get song revision",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, rev)
+}
+
+// @Summary      Roll back song
+// @Description  restore song and its verses as they were after revision, deleted song is recreated.
+// @Description  Timing of lines is not restored. Rollback is recorded as a new revision.
+// @Produce      json
+// @Success      200  {object} entities.SongSnapshot
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/{id}/history/{num}/rollback [post]
+func (h *handler) RollbackSong(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	num, err := strconv.Atoi(chi.URLParam(r, "num"))
+	if err != nil {
+		ReturnHttpError(w, r, badRequest("num", err))
+		return
+	}
+
+	snapshot, err := h.uc.RollbackSong(r.Context(), chi.URLParam(r, "id"), num, caller(r))
+	if err != nil {
+		h.log.Error("Failed to roll back song",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	setSongETag(w, &snapshot.Song)
+	h.writeJSON(w, r, http.StatusOK, snapshot)
+}
@@ -0,0 +1,104 @@
+package delivery
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"testEM/internal/entities"
+	"time"
+
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+// timingFormat takes the format from the query, falling back to the content
+// type of the body. Empty format is detected from the body.
+func timingFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-lrc", "text/x-lrc":
+		return entities.TimingFormatLRC
+	case "application/x-subrip", "application/srt", "text/srt":
+		return entities.TimingFormatSRT
+	}
+	return ""
+}
+
+// playbackOffset reads the offset in milliseconds or as a duration like 1m2.5s.
+func playbackOffset(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("offset")
+	if value == "" {
+		return 0, entities.NewValidationError("offset", "is required")
+	}
+	if ms, err := strconv.Atoi(value); err == nil {
+		return ms, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, entities.NewValidationError("offset", "must be milliseconds or duration")
+	}
+	return int(d / time.Millisecond), nil
+}
+
+// @Summary      Upload timing
+// @Description  upload lrc or srt timing of song, format is taken from format query or content type and is detected from body otherwise.
+// @Description  Lines of the file are aligned with lines of verses by text, replacing timing uploaded before;
+// @Description  lines which couldn't be aligned are stored without timestamps.
+// @Accept       application/x-lrc
+// @Accept       application/x-subrip
+// @Produce      json
+// @Success      200  {object} entities.TimingReport
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/{id}/timing [put]
+func (h *handler) SyncLyrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	report, err := h.uc.SyncLyrics(r.Context(), chi.URLParam(r, "id"), timingFormat(r), r.Body, caller(r))
+	if err != nil {
+		h.log.Error("Failed to sync song lyrics",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, report)
+}
+
+// @Summary      Get active line
+// @Description  get line of song sung at playback offset given in milliseconds or as duration like 1m2.5s
+// @Produce      json
+// @Success      200  {object} entities.Line
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/{id}/lines/active [get]
+func (h *handler) GetActiveLine(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	offset, err := playbackOffset(r)
+	if err != nil {
+		ReturnHttpError(w, r, err)
+		return
+	}
+
+	l, err := h.uc.GetActiveLine(r.Context(), chi.URLParam(r, "id"), offset)
+	if err != nil {
+		h.log.Error("Failed to get active line",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, l)
+}
@@ -0,0 +1,249 @@
+package delivery
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"testEM/internal/entities"
+	"time"
+
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+// @Summary      Get verse
+// @Description  get single verse of song by its number
+// @Produce      json
+// @Success      200  {object} entities.Verse
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/{id}/verses/{num} [get]
+func (h *handler) GetVerse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	songID := chi.URLParam(r, "id")
+	num, err := strconv.Atoi(chi.URLParam(r, "num"))
+	if err != nil {
+		h.log.Error("Failed to parse verse number",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("num", err))
+		return
+	}
+
+	v, err := h.uc.GetVerse(r.Context(), songID, num)
+	if err != nil {
+		h.log.Error("Failed to get verse",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeVerse(w, r, http.StatusOK, v)
+}
+
+// @Summary      Replace verse
+// @Description  replace content of verse with specified number
+// @Produce      json
+// @Success      200  {object} entities.Verse
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/{id}/verses/{num} [put]
+func (h *handler) ReplaceVerse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	songID := chi.URLParam(r, "id")
+	num, err := strconv.Atoi(chi.URLParam(r, "num"))
+	if err != nil {
+		h.log.Error("Failed to parse verse number",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("num", err))
+		return
+	}
+
+	dto := entities.ReplaceVerseDTO{}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &dto)
+	}
+	if err != nil {
+		h.log.Error("Failed to read body",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("body", err))
+		return
+	}
+
+	v, err := h.uc.ReplaceVerse(r.Context(), songID, num, dto, caller(r))
+	if err != nil {
+		h.log.Error("Failed to replace verse",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeVerse(w, r, http.StatusOK, v)
+}
+
+// @Summary      Insert verse
+// @Description  insert verse at specified number, following verses are shifted down; appends when number is omitted
+// @Produce      json
+// @Success      201  {object} entities.Verse
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/{id}/verses [post]
+func (h *handler) InsertVerse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	songID := chi.URLParam(r, "id")
+
+	dto := entities.AddVerseDTO{}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &dto)
+	}
+	if err != nil {
+		h.log.Error("Failed to read body",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("body", err))
+		return
+	}
+
+	v, err := h.uc.InsertVerse(r.Context(), songID, dto, caller(r))
+	if err != nil {
+		h.log.Error("Failed to insert verse",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeVerse(w, r, http.StatusCreated, v)
+}
+
+// @Summary      Move verse
+// @Description  move verse to another position, verses in between are renumbered
+// @Produce      json
+// @Success      200  {object} entities.Verse
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/{id}/verses/{num}/move [post]
+func (h *handler) MoveVerse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	songID := chi.URLParam(r, "id")
+	num, err := strconv.Atoi(chi.URLParam(r, "num"))
+	if err != nil {
+		h.log.Error("Failed to parse verse number",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("num", err))
+		return
+	}
+
+	dto := entities.MoveVerseDTO{}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &dto)
+	}
+	if err != nil {
+		h.log.Error("Failed to read body",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("body", err))
+		return
+	}
+
+	v, err := h.uc.MoveVerse(r.Context(), songID, num, dto, caller(r))
+	if err != nil {
+		h.log.Error("Failed to move verse",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeVerse(w, r, http.StatusOK, v)
+}
+
+// @Summary      Delete verse
+// @Description  delete verse with specified number, following verses are shifted up
+// @Produce      json
+// @Success      204  {object} nil
+// @Failure      400  {object} HttpError
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/{id}/verses/{num} [delete]
+func (h *handler) DeleteVerse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	songID := chi.URLParam(r, "id")
+	num, err := strconv.Atoi(chi.URLParam(r, "num"))
+	if err != nil {
+		h.log.Error("Failed to parse verse number",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, badRequest("num", err))
+		return
+	}
+
+	err = h.uc.DeleteVerse(r.Context(), songID, num, caller(r))
+	if err != nil {
+		h.log.Error("Failed to delete verse",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) writeVerse(w http.ResponseWriter, r *http.Request, status int, v *entities.Verse) {
+	h.writeJSON(w, r, status, v)
+}
+
+// @Summary      Get song structure
+// @Description  get form of song like A-B-A-B-C-B with its verses, verses of the same part are identical or near-identical
+// @Produce      json
+// @Success      200  {object} entities.SongStructure
+// @Failure      404  {object} HttpError
+// @Failure      500  {object} HttpError
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /songs/{id}/structure [get]
+func (h *handler) GetStructure(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	s, err := h.uc.GetStructure(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.log.Error("Failed to get song structure",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		ReturnHttpError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, s)
+}
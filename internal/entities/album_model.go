@@ -0,0 +1,51 @@
+package entities
+
+import "time"
+
+type Album struct {
+	ID          *string       `json:"id"`
+	GroupID     *string       `json:"groupId"`
+	Title       *string       `json:"title"`
+	ReleaseDate *time.Time    `json:"releaseDate"`
+	Tracks      []*AlbumTrack `json:"tracks,omitempty"`
+}
+
+type AlbumTrack struct {
+	Number int   `json:"num"`
+	Song   *Song `json:"song"`
+}
+
+type AddAlbumDTO struct {
+	GroupID     *string `json:"groupId" validate:"required"`
+	Title       *string `json:"title" validate:"required,max=1024"`
+	ReleaseDate *string `json:"releaseDate" validate:"date=02.01.2006"`
+}
+
+type PatchAlbumDTO struct {
+	GroupID     *string `json:"groupId" validate:"notblank"`
+	Title       *string `json:"title" validate:"notblank,max=1024"`
+	ReleaseDate *string `json:"releaseDate" validate:"date=02.01.2006"`
+}
+
+type AlbumSearchOptions struct {
+	Title     *string `query:"title" validate:"max=1024"`
+	GroupID   *string `query:"groupId"`
+	Page      *int    `query:"page" validate:"min=1,max=100000"`
+	PerPage   *int    `query:"perPage" validate:"min=1,max=1000"`
+	Cursor    *string `query:"cursor"`
+	WithTotal *bool   `query:"withTotal"`
+}
+
+type AlbumsWrapper struct {
+	Albums []*Album `json:"albums"`
+	Page
+}
+
+type AddTrackDTO struct {
+	SongID *string `json:"songId" validate:"required"`
+	Number *int    `json:"num" validate:"min=1"`
+}
+
+type MoveTrackDTO struct {
+	To *int `json:"to" validate:"required,min=1"`
+}
@@ -0,0 +1,89 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Caller identifies who makes a change and the request it came with, the
+// request id and client IP are empty for changes made by the service itself.
+type Caller struct {
+	Actor     string
+	RequestID string
+	ClientIP  string
+}
+
+// Resources of audit entries, changes of verses, lines, tracks and playlist
+// entries are recorded on the resource they belong to.
+const (
+	AuditSong     = "song"
+	AuditGroup    = "group"
+	AuditAlbum    = "album"
+	AuditPlaylist = "playlist"
+)
+
+const (
+	AuditSongAdd      = "song.add"
+	AuditSongImport   = "song.import"
+	AuditSongPatch    = "song.patch"
+	AuditSongEnrich   = "song.enrich"
+	AuditSongDelete   = "song.delete"
+	AuditSongRestore  = "song.restore"
+	AuditSongPurge    = "song.purge"
+	AuditSongRollback = "song.rollback"
+	AuditSongRegroup  = "song.regroup"
+
+	AuditVerseInsert  = "verse.insert"
+	AuditVerseReplace = "verse.replace"
+	AuditVerseMove    = "verse.move"
+	AuditVerseDelete  = "verse.delete"
+	AuditLinesSync    = "lines.sync"
+
+	AuditGroupAdd    = "group.add"
+	AuditGroupRename = "group.rename"
+	AuditGroupDelete = "group.delete"
+
+	AuditAlbumAdd         = "album.add"
+	AuditAlbumPatch       = "album.patch"
+	AuditAlbumDelete      = "album.delete"
+	AuditAlbumTrackAdd    = "album.track.add"
+	AuditAlbumTrackRemove = "album.track.remove"
+	AuditAlbumTrackMove   = "album.track.move"
+
+	AuditPlaylistAdd        = "playlist.add"
+	AuditPlaylistPatch      = "playlist.patch"
+	AuditPlaylistDelete     = "playlist.delete"
+	AuditPlaylistSongAdd    = "playlist.song.add"
+	AuditPlaylistSongRemove = "playlist.song.remove"
+	AuditPlaylistSongMove   = "playlist.song.move"
+)
+
+// AuditEntry is a single change, Before and After hold the resource as it
+// was around the change and are empty when it didn't exist.
+type AuditEntry struct {
+	ID         string          `json:"id"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	Resource   string          `json:"resource"`
+	ResourceID string          `json:"resourceId"`
+	Before     json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After      json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	RequestID  *string         `json:"requestId,omitempty"`
+	ClientIP   *string         `json:"clientIp,omitempty"`
+}
+
+type AuditSearchOptions struct {
+	Actor      *string    `query:"actor" validate:"max=256"`
+	Resource   *string    `query:"resource" validate:"oneof=song group album playlist"`
+	ResourceID *string    `query:"resourceId" validate:"max=64"`
+	From       *time.Time `query:"from" layout:"2006-01-02T15:04:05Z07:00"`
+	To         *time.Time `query:"to" layout:"2006-01-02T15:04:05Z07:00"`
+	Page       *int       `query:"page" validate:"min=1,max=100000"`
+	PerPage    *int       `query:"perPage" validate:"min=1,max=1000"`
+}
+
+type AuditWrapper struct {
+	Entries []*AuditEntry `json:"entries"`
+	Page
+}
@@ -0,0 +1,14 @@
+package entities
+
+const (
+	EnrichmentPending  = "pending"
+	EnrichmentEnriched = "enriched"
+	EnrichmentFailed   = "failed"
+)
+
+type EnrichmentJob struct {
+	ID       string
+	SongID   string
+	Attempts int
+	Splitter *string
+}
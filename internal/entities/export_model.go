@@ -0,0 +1,13 @@
+package entities
+
+const (
+	ExportFormatJSON   = "json"
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+)
+
+// ExportedSong is a song together with its lyrics as written by the catalog export.
+type ExportedSong struct {
+	Song
+	Verses []*Verse `json:"verses"`
+}
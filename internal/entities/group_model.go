@@ -0,0 +1,23 @@
+package entities
+
+type Group struct {
+	ID   *string `json:"id"`
+	Name *string `json:"name"`
+}
+
+type GroupDTO struct {
+	Name *string `json:"name" validate:"required,max=1024"`
+}
+
+type GroupSearchOptions struct {
+	Name      *string `query:"name" validate:"max=1024"`
+	Page      *int    `query:"page" validate:"min=1,max=100000"`
+	PerPage   *int    `query:"perPage" validate:"min=1,max=1000"`
+	Cursor    *string `query:"cursor"`
+	WithTotal *bool   `query:"withTotal"`
+}
+
+type GroupsWrapper struct {
+	Groups []*Group `json:"groups"`
+	Page
+}
@@ -0,0 +1,34 @@
+package entities
+
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+
+	ImportCreated = "created"
+	ImportFailed  = "failed"
+)
+
+// ImportRecord is a single song of a bulk import. Missing release date,
+// link or lyrics are fetched from the external API.
+type ImportRecord struct {
+	Group       *string `json:"group" validate:"required,max=1024"`
+	Song        *string `json:"song" validate:"required,max=1024"`
+	ReleaseDate *string `json:"releaseDate" validate:"date=02.01.2006"`
+	Link        *string `json:"link" validate:"url,max=1024"`
+	Lyrics      *string `json:"lyrics"`
+}
+
+type ImportRowResult struct {
+	Row    int              `json:"row"`
+	Status string           `json:"status"`
+	SongID *string          `json:"songId,omitempty"`
+	Error  string           `json:"error,omitempty"`
+	Errors []FieldViolation `json:"errors,omitempty"`
+}
+
+type ImportReport struct {
+	Total   int                `json:"total"`
+	Created int                `json:"created"`
+	Failed  int                `json:"failed"`
+	Rows    []*ImportRowResult `json:"rows"`
+}
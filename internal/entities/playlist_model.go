@@ -0,0 +1,45 @@
+package entities
+
+type Playlist struct {
+	ID          *string         `json:"id"`
+	Name        *string         `json:"name"`
+	Description *string         `json:"description"`
+	Songs       []*PlaylistSong `json:"songs,omitempty"`
+}
+
+type PlaylistSong struct {
+	Number int   `json:"num"`
+	Song   *Song `json:"song"`
+}
+
+type AddPlaylistDTO struct {
+	Name        *string `json:"name" validate:"required,max=1024"`
+	Description *string `json:"description" validate:"max=4096"`
+}
+
+type PatchPlaylistDTO struct {
+	Name        *string `json:"name" validate:"notblank,max=1024"`
+	Description *string `json:"description" validate:"max=4096"`
+}
+
+type PlaylistSearchOptions struct {
+	Name      *string `query:"name" validate:"max=1024"`
+	Page      *int    `query:"page" validate:"min=1,max=100000"`
+	PerPage   *int    `query:"perPage" validate:"min=1,max=1000"`
+	Cursor    *string `query:"cursor"`
+	WithTotal *bool   `query:"withTotal"`
+}
+
+type PlaylistsWrapper struct {
+	Playlists []*Playlist `json:"playlists"`
+	Page
+}
+
+type AddPlaylistSongDTO struct {
+	SongID *string `json:"songId" validate:"required"`
+	Number *int    `json:"num" validate:"min=1"`
+}
+
+type MovePlaylistSongDTO struct {
+	To *int `json:"to" validate:"required,min=1"`
+}
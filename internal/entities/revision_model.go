@@ -0,0 +1,55 @@
+package entities
+
+import "time"
+
+const (
+	RevisionCreate   = "create"
+	RevisionUpdate   = "update"
+	RevisionDelete   = "delete"
+	RevisionRollback = "rollback"
+	RevisionRestore  = "restore"
+)
+
+// Actors of changes made by the service itself.
+const (
+	ActorAnonymous  = "anonymous"
+	ActorEnrichment = "enrichment"
+	ActorImport     = "import"
+	ActorPurge      = "purge"
+)
+
+// SongSnapshot is the state of a song with its verses after a revision,
+// it is empty for deletions.
+type SongSnapshot struct {
+	Song   Song     `json:"song"`
+	Verses []*Verse `json:"verses"`
+}
+
+// FieldChange is a single difference between two snapshots, verses are
+// addressed by number like verses[2] and verses[2].label.
+type FieldChange struct {
+	Field string  `json:"field"`
+	Old   *string `json:"old"`
+	New   *string `json:"new"`
+}
+
+type Revision struct {
+	SongID    string         `json:"songId"`
+	Number    int            `json:"num"`
+	Action    string         `json:"action"`
+	Actor     string         `json:"actor"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Diff      []*FieldChange `json:"diff"`
+	Snapshot  *SongSnapshot  `json:"snapshot,omitempty"`
+}
+
+type RevisionSearchOptions struct {
+	SongID  *string `validate:"required"`
+	Page    *int    `query:"page" validate:"min=1,max=100000"`
+	PerPage *int    `query:"perPage" validate:"min=1,max=1000"`
+}
+
+type RevisionsWrapper struct {
+	Revisions []*Revision `json:"revisions"`
+	Page
+}
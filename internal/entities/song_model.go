@@ -3,32 +3,60 @@ package entities
 import "time"
 
 type AddSongDTO struct {
-	Group *string `json:"group"`
-	Song  *string `json:"song"`
+	Group    *string `json:"group" validate:"required,max=1024"`
+	Song     *string `json:"song" validate:"required,max=1024"`
+	Splitter *string `json:"splitter" validate:"oneof=blank crlf sections"`
 }
 
 type PatchSongDTO struct {
-	Group       *string `json:"group"`
-	Song        *string `json:"song"`
-	ReleaseDate *string `json:"releaseDate"`
-	Link        *string `json:"link"`
+	Group       *string `json:"group" validate:"notblank,max=1024"`
+	Song        *string `json:"song" validate:"notblank,max=1024"`
+	ReleaseDate *string `json:"releaseDate" validate:"date=02.01.2006"`
+	Link        *string `json:"link" validate:"url,max=1024"`
 }
 
 type Song struct {
-	ID          *string    `json:"id"`
-	Group       *string    `json:"group"`
-	Song        *string    `json:"song"`
-	ReleaseDate *time.Time `json:"releaseDate"`
-	Link        *string    `json:"link"`
+	ID               *string       `json:"id"`
+	Group            *string       `json:"group"`
+	GroupID          *string       `json:"groupId,omitempty"`
+	Song             *string       `json:"song"`
+	ReleaseDate      *time.Time    `json:"releaseDate"`
+	Link             *string       `json:"link"`
+	EnrichmentStatus *string       `json:"enrichmentStatus,omitempty"`
+	DeletedAt        *time.Time    `json:"deletedAt,omitempty"`
+	Version          *int          `json:"version,omitempty"`
+	Rank             *float64      `json:"rank,omitempty"`
+	Matches          []*VerseMatch `json:"matches,omitempty"`
 }
 
+const (
+	MatchExact    = "exact"
+	MatchPrefix   = "prefix"
+	MatchContains = "contains"
+)
+
 type SongSearchOptions struct {
-	Group             *string
-	Song              *string
-	ReleaseDateBefore *time.Time
-	ReleaseDateAfter  *time.Time
-	Page              *int
-	PerPage           *int
+	Group             *string    `query:"group" validate:"max=1024"`
+	Song              *string    `query:"song" validate:"max=1024"`
+	ReleaseDateBefore *time.Time `query:"releaseDateBefore" layout:"02.01.2006"`
+	ReleaseDateAfter  *time.Time `query:"releaseDateAfter" layout:"02.01.2006"`
+	Lyrics            *string    `query:"lyrics" validate:"notblank,max=1024"`
+	Groups            []string   `query:"groups" validate:"max=100"`
+	GroupID           *string    `query:"groupId"`
+	Album             *string    `query:"album"`
+	Match             *string    `query:"match" validate:"oneof=exact prefix contains"`
+	HasLink           *bool      `query:"hasLink"`
+	HasLyrics         *bool      `query:"hasLyrics"`
+	Sort              []string   `query:"sort" validate:"max=4,oneof=id -id song -song group -group releaseDate -releaseDate"`
+	Page              *int       `query:"page" validate:"min=1,max=100000"`
+	PerPage           *int       `query:"perPage" validate:"min=1,max=1000"`
+	Cursor            *string    `query:"cursor"`
+	WithTotal         *bool      `query:"withTotal"`
+}
+
+type DeletedSongSearchOptions struct {
+	Page    *int `query:"page" validate:"min=1,max=100000"`
+	PerPage *int `query:"perPage" validate:"min=1,max=1000"`
 }
 
 type SongDetail struct {
@@ -39,5 +67,13 @@ type SongDetail struct {
 
 type SongsWrapper struct {
 	Songs []*Song `json:"songs"`
-	Total int     `json:"total"`
+	Page
+}
+
+// Page describes position of a listing. Total is only filled when requested
+// or when paging by page number, cursors only when paging by cursor.
+type Page struct {
+	Total      *int    `json:"total,omitempty"`
+	NextCursor *string `json:"nextCursor,omitempty"`
+	PrevCursor *string `json:"prevCursor,omitempty"`
 }
@@ -1,17 +1,102 @@
 package entities
 
+const (
+	SplitterBlankLine = "blank"
+	SplitterCRLF      = "crlf"
+	SplitterSections  = "sections"
+)
+
+// Section labels detected by the sections splitter.
+const (
+	SectionVerse     = "verse"
+	SectionPreChorus = "pre-chorus"
+	SectionChorus    = "chorus"
+	SectionBridge    = "bridge"
+	SectionIntro     = "intro"
+	SectionOutro     = "outro"
+)
+
 type Verse struct {
-	SongID  string `json:"song_id"`
-	Number  int    `json:"num"`
-	Content string `json:"content"`
+	SongID  string  `json:"song_id"`
+	Number  int     `json:"num"`
+	Content string  `json:"content"`
+	Label   *string `json:"label,omitempty"`
+	Lines   []*Line `json:"lines,omitempty"`
+	// Part is shared by identical and near-identical verses of the song.
+	Part int `json:"-"`
+}
+
+// Line is a single line of a verse, timestamps are offsets from the start
+// of the song and are present only when timing was uploaded.
+type Line struct {
+	VerseNumber int    `json:"verseNum"`
+	Number      int    `json:"num"`
+	Content     string `json:"content"`
+	StartMs     *int   `json:"startMs"`
+	EndMs       *int   `json:"endMs"`
+}
+
+const (
+	TimingFormatLRC = "lrc"
+	TimingFormatSRT = "srt"
+)
+
+// TimingReport is the outcome of uploading timing, lines of verses that
+// couldn't be matched with the file are stored without timestamps.
+type TimingReport struct {
+	Lines   int      `json:"lines"`
+	Aligned int      `json:"aligned"`
+	Verses  []*Verse `json:"verses"`
+}
+
+type VerseMatch struct {
+	Number  int     `json:"num"`
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
 }
 
 type VerseSearchOptions struct {
-	SongID  *string
-	Page    *int
-	PerPage *int
+	SongID    *string `validate:"required"`
+	Page      *int    `query:"page" validate:"min=1,max=100000"`
+	PerPage   *int    `query:"perPage" validate:"min=1,max=1000"`
+	Cursor    *string `query:"cursor"`
+	WithTotal *bool   `query:"withTotal"`
 }
 type VersesWrapper struct {
 	Verses []*Verse `json:"verses"`
-	Total  int      `json:"total"`
+	Page
+}
+
+type AddVerseDTO struct {
+	Number  *int    `json:"num" validate:"min=1"`
+	Content *string `json:"content" validate:"required"`
+	Label   *string `json:"label" validate:"oneof=verse pre-chorus chorus bridge intro outro"`
+}
+
+type ReplaceVerseDTO struct {
+	Content *string `json:"content" validate:"required"`
+	Label   *string `json:"label" validate:"oneof=verse pre-chorus chorus bridge intro outro"`
+}
+
+type MoveVerseDTO struct {
+	To *int `json:"to" validate:"required,min=1"`
+}
+
+// Lyrics is the whole text of a song split into verses in order.
+type Lyrics struct {
+	Song   *Song
+	Verses []*Verse
+}
+
+// SongStructure is the form of a song like A-B-A-B-C-B, verses named by
+// the same letter are identical or near-identical.
+type SongStructure struct {
+	Form   string            `json:"form"`
+	Parts  int               `json:"parts"`
+	Verses []*StructureVerse `json:"verses"`
+}
+
+type StructureVerse struct {
+	*Verse
+	PartName string `json:"part"`
 }
@@ -0,0 +1,464 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testEM/internal/entities"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+var albumColumns = []string{"id", "group_id", "title", "release_date"}
+
+type AlbumStorage struct {
+	db  Querier
+	log *zap.Logger
+}
+
+func NewAlbumStorage(db Querier, log *zap.Logger) *AlbumStorage {
+	return &AlbumStorage{
+		db:  db,
+		log: log,
+	}
+}
+
+func scanAlbum(row scanner) (*entities.Album, error) {
+	a := entities.Album{}
+	if err := row.Scan(&a.ID, &a.GroupID, &a.Title, &a.ReleaseDate); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (st *AlbumStorage) AddAlbum(ctx context.Context, album entities.Album) (*entities.Album, error) {
+	builder := sq.Insert("albums").
+		Columns("group_id", "title", "release_date").
+		Values(album.GroupID, album.Title, album.ReleaseDate).
+		Suffix("RETURNING " + strings.Join(albumColumns, ", ")).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to add album",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	a, err := scanAlbum(st.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		st.log.Debug("Failed to execute query in AddAlbum",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "group", *album.GroupID)
+	}
+	return a, err
+}
+
+func (st *AlbumStorage) GetAlbum(ctx context.Context, id string) (*entities.Album, error) {
+	builder := sq.Select(albumColumns...).From("albums").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get album",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	a, err := scanAlbum(st.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &entities.NotFoundError{Resource: "album", ID: id}
+		}
+
+		st.log.Debug("Failed to execute query in GetAlbum",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "album", id)
+	}
+	return a, err
+}
+
+func (st *AlbumStorage) GetAlbums(ctx context.Context, opts *entities.AlbumSearchOptions) ([]*entities.Album, entities.Page, error) {
+	keyset := isKeyset(opts.Page, opts.Cursor, opts.PerPage)
+	keys := []sortKey{{Field: "id", Expr: "id"}}
+	cur, err := decodeCursor(opts.Cursor, len(keys))
+	if err != nil {
+		return nil, entities.Page{}, err
+	}
+
+	builder := sq.Select(albumColumns...).From("albums")
+	builder = st.AddSearchOptionsToBuilder(builder, opts, true)
+	if keyset {
+		builder = applyKeyset(builder, keys, cur, pageSize(opts.PerPage))
+	} else {
+		builder = orderBy(builder, keys, false)
+	}
+	builder = builder.PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get albums",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, entities.Page{}, err
+	}
+
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in GetAlbums",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, entities.Page{}, err
+	}
+	defer rows.Close()
+
+	albums := make([]*entities.Album, 0)
+	for rows.Next() {
+		a, err := scanAlbum(rows)
+		if err != nil {
+			st.log.Debug("Failed to scan row in GetAlbums")
+			return nil, entities.Page{}, err
+		}
+		albums = append(albums, a)
+	}
+	if err = rows.Err(); err != nil {
+		st.log.Debug("Failed to scan rows in GetAlbums")
+		return nil, entities.Page{}, err
+	}
+
+	page := entities.Page{}
+	if keyset {
+		albums, page = buildPage(albums, cur, pageSize(opts.PerPage), func(a *entities.Album) []string {
+			return []string{*a.ID}
+		})
+		if opts.WithTotal == nil || !*opts.WithTotal {
+			return albums, page, err
+		}
+	}
+
+	builder = sq.Select("count(*)").From("albums")
+	builder = st.AddSearchOptionsToBuilder(builder, opts, false)
+	builder = builder.PlaceholderFormat(sq.Dollar)
+
+	query, args, err = builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get total albums",
+			zap.String("message", err.Error()),
+		)
+		return nil, entities.Page{}, err
+	}
+
+	var count int
+	err = st.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		st.log.Debug("Failed to execute query for total albums in GetAlbums",
+			zap.String("message", err.Error()),
+		)
+		return nil, entities.Page{}, err
+	}
+	page.Total = &count
+
+	return albums, page, err
+}
+
+func (st *AlbumStorage) UpdateAlbum(ctx context.Context, id string, album entities.Album) (*entities.Album, error) {
+	builder := sq.Update("albums").Where(sq.Eq{"id": id})
+	if album.GroupID != nil {
+		builder = builder.Set("group_id", album.GroupID)
+	}
+	if album.Title != nil {
+		builder = builder.Set("title", album.Title)
+	}
+	if album.ReleaseDate != nil {
+		builder = builder.Set("release_date", album.ReleaseDate)
+	}
+	if album.GroupID == nil && album.Title == nil && album.ReleaseDate == nil {
+		return st.GetAlbum(ctx, id)
+	}
+	builder = builder.Suffix("RETURNING " + strings.Join(albumColumns, ", ")).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to update album",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	a, err := scanAlbum(st.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &entities.NotFoundError{Resource: "album", ID: id}
+		}
+
+		st.log.Debug("Failed to execute query in UpdateAlbum",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		if album.GroupID != nil {
+			return nil, mapError(err, "group", *album.GroupID)
+		}
+		return nil, mapError(err, "album", id)
+	}
+	return a, err
+}
+
+func (st *AlbumStorage) DeleteAlbum(ctx context.Context, id string) error {
+	builder := sq.Delete("albums").Where(sq.Eq{"id": id}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to delete album",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	res, err := st.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in DeleteAlbum",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return mapError(err, "album", id)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return &entities.NotFoundError{Resource: "album", ID: id}
+	}
+	return err
+}
+
+// GetTracks returns songs of the album ordered by track number.
+func (st *AlbumStorage) GetTracks(ctx context.Context, albumId string) ([]*entities.AlbumTrack, error) {
+	builder := sq.Select(append([]string{"t.num"}, qualify("songs", songColumns)...)...).
+		From("album_tracks t").
+		Join("songs ON songs.id = t.song_id").
+		Where(sq.Eq{"t.album_id": albumId}).
+		Where("songs.deleted_at IS NULL").
+		OrderBy("t.num").
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get tracks",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in GetTracks",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "album", albumId)
+	}
+	defer rows.Close()
+
+	tracks := make([]*entities.AlbumTrack, 0)
+	for rows.Next() {
+		t := entities.AlbumTrack{Song: &entities.Song{}}
+		if err := rows.Scan(append([]any{&t.Number}, songFields(t.Song)...)...); err != nil {
+			st.log.Debug("Failed to scan row in GetTracks")
+			return nil, err
+		}
+		tracks = append(tracks, &t)
+	}
+	if err = rows.Err(); err != nil {
+		st.log.Debug("Failed to scan rows in GetTracks")
+		return nil, err
+	}
+	return tracks, err
+}
+
+func (st *AlbumStorage) CountTracks(ctx context.Context, albumId string) (int, error) {
+	builder := sq.Select("count(*)").From("album_tracks").
+		Where(sq.Eq{"album_id": albumId}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to count tracks",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return 0, err
+	}
+
+	var count int
+	err = st.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		st.log.Debug("Failed to execute query in CountTracks",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return 0, mapError(err, "album", albumId)
+	}
+	return count, err
+}
+
+// AddTrack puts the song at the given track number, following tracks are
+// shifted down within the same statement.
+func (st *AlbumStorage) AddTrack(ctx context.Context, albumId string, songId string, num int) error {
+	builder := sq.Insert("album_tracks").
+		Prefix("WITH shifted AS (UPDATE album_tracks SET num = num + 1 WHERE album_id = ? AND num >= ?)", albumId, num).
+		Columns("album_id", "song_id", "num").
+		Values(albumId, songId, num).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to add track",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	_, err = st.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in AddTrack",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return mapError(err, "song", songId)
+	}
+	return err
+}
+
+// RemoveTrack removes the song from the album and closes the gap in numbering.
+func (st *AlbumStorage) RemoveTrack(ctx context.Context, albumId string, songId string) error {
+	builder := sq.Select("count(*)").From("deleted").
+		Prefix("WITH deleted AS (DELETE FROM album_tracks WHERE album_id = ? AND song_id = ? RETURNING num), "+
+			"shifted AS (UPDATE album_tracks SET num = num - 1 WHERE album_id = ? AND num > (SELECT num FROM deleted))",
+			albumId, songId, albumId).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to remove track",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	var deleted int
+	err = st.db.QueryRowContext(ctx, query, args...).Scan(&deleted)
+	if err != nil {
+		st.log.Debug("Failed to execute query in RemoveTrack",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return mapError(err, "track", songId)
+	}
+	if deleted == 0 {
+		return &entities.NotFoundError{Resource: "track", ID: songId}
+	}
+	return err
+}
+
+// RemoveSongTracks removes the song from every album it is on, closing
+// the gaps in numbering. Used before the song itself is deleted.
+func (st *AlbumStorage) RemoveSongTracks(ctx context.Context, songId string) error {
+	builder := sq.Update("album_tracks t").
+		Prefix("WITH deleted AS (DELETE FROM album_tracks WHERE song_id = ? RETURNING album_id, num)", songId).
+		Set("num", sq.Expr("t.num - 1")).
+		From("deleted d").
+		Where("t.album_id = d.album_id AND t.num > d.num").
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to remove song tracks",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	_, err = st.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in RemoveSongTracks",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return mapError(err, "song", songId)
+	}
+	return err
+}
+
+// MoveTrack moves the song to another track number, shifting the tracks
+// in between by one within a single statement.
+func (st *AlbumStorage) MoveTrack(ctx context.Context, albumId string, songId string, to int) error {
+	builder := sq.Update("album_tracks").
+		Prefix("WITH cur AS (SELECT num FROM album_tracks WHERE album_id = ? AND song_id = ?)", albumId, songId).
+		Set("num", sq.Expr("CASE WHEN song_id = ? THEN ? WHEN (SELECT num FROM cur) < ? THEN num - 1 ELSE num + 1 END", songId, to, to)).
+		Where(sq.Eq{"album_id": albumId}).
+		Where(sq.Expr("num BETWEEN least((SELECT num FROM cur), ?) AND greatest((SELECT num FROM cur), ?)", to, to)).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to move track",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	res, err := st.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in MoveTrack",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return mapError(err, "track", songId)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return &entities.NotFoundError{Resource: "track", ID: songId}
+	}
+	return err
+}
+
+func (st *AlbumStorage) AddSearchOptionsToBuilder(builder sq.SelectBuilder, opts *entities.AlbumSearchOptions, enablePagination bool) sq.SelectBuilder {
+	if opts.Title != nil {
+		builder = builder.Where(sq.Expr("lower(title) LIKE ?", "%"+likeEscaper.Replace(strings.ToLower(*opts.Title))+"%"))
+	}
+
+	if opts.GroupID != nil {
+		builder = builder.Where(sq.Eq{"group_id": *opts.GroupID})
+	}
+
+	if enablePagination && opts.Page != nil && opts.PerPage != nil {
+		builder = builder.Offset((uint64)(*opts.PerPage * (*opts.Page - 1))).Limit(uint64(*opts.PerPage))
+	}
+	return builder
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testEM/internal/entities"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+type AuditStorage struct {
+	db  Querier
+	log *zap.Logger
+}
+
+func NewAuditStorage(db Querier, log *zap.Logger) *AuditStorage {
+	return &AuditStorage{
+		db:  db,
+		log: log,
+	}
+}
+
+var auditColumns = []string{"id", "created_at", "actor", "action", "resource", "resource_id", "before", "after", "request_id", "client_ip"}
+
+func scanAuditEntry(row scanner) (*entities.AuditEntry, error) {
+	e := entities.AuditEntry{}
+	var before, after []byte
+	err := row.Scan(&e.ID, &e.CreatedAt, &e.Actor, &e.Action, &e.Resource, &e.ResourceID,
+		&before, &after, &e.RequestID, &e.ClientIP)
+	if err != nil {
+		return nil, err
+	}
+	if before != nil {
+		e.Before = json.RawMessage(before)
+	}
+	if after != nil {
+		e.After = json.RawMessage(after)
+	}
+	return &e, nil
+}
+
+// jsonParam passes v as json text, nil values are stored as NULL.
+func jsonParam(v any) (*string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if string(data) == "null" {
+		return nil, nil
+	}
+	s := string(data)
+	return &s, nil
+}
+
+// AddEntry appends the entry to the audit log, entries are never changed.
+func (st *AuditStorage) AddEntry(ctx context.Context, e entities.AuditEntry, before, after any) error {
+	beforeParam, err := jsonParam(before)
+	if err != nil {
+		return err
+	}
+	afterParam, err := jsonParam(after)
+	if err != nil {
+		return err
+	}
+
+	builder := sq.Insert("audit_log").
+		Columns("actor", "action", "resource", "resource_id", "before", "after", "request_id", "client_ip").
+		Values(e.Actor, e.Action, e.Resource, e.ResourceID, beforeParam, afterParam, e.RequestID, e.ClientIP).
+		PlaceholderFormat(sq.Dollar)
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to add audit entry",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	_, err = st.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in AddEntry",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+	return err
+}
+
+// GetEntries lists entries matching the options, newest first.
+func (st *AuditStorage) GetEntries(ctx context.Context, opts *entities.AuditSearchOptions) ([]*entities.AuditEntry, int, error) {
+	builder := sq.Select(auditColumns...).From("audit_log")
+	builder = st.AddSearchOptionsToBuilder(builder, opts, true)
+	builder = builder.OrderBy("created_at DESC", "id DESC").PlaceholderFormat(sq.Dollar)
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get audit entries",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, 0, err
+	}
+
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in GetEntries",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := make([]*entities.AuditEntry, 0)
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			st.log.Debug("Failed to scan row in GetEntries")
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		st.log.Debug("Failed to scan rows in GetEntries")
+		return nil, 0, err
+	}
+
+	builder = sq.Select("count(*)").From("audit_log")
+	builder = st.AddSearchOptionsToBuilder(builder, opts, false)
+	builder = builder.PlaceholderFormat(sq.Dollar)
+	query, args, err = builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to count audit entries",
+			zap.String("message", err.Error()),
+		)
+		return nil, 0, err
+	}
+
+	var count int
+	err = st.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		st.log.Debug("Failed to execute query to count audit entries",
+			zap.String("message", err.Error()),
+		)
+		return nil, 0, err
+	}
+	return entries, count, err
+}
+
+func (st *AuditStorage) AddSearchOptionsToBuilder(builder sq.SelectBuilder, opts *entities.AuditSearchOptions, enablePagination bool) sq.SelectBuilder {
+	if opts.Actor != nil {
+		builder = builder.Where(sq.Eq{"actor": *opts.Actor})
+	}
+
+	if opts.Resource != nil {
+		builder = builder.Where(sq.Eq{"resource": *opts.Resource})
+	}
+
+	if opts.ResourceID != nil {
+		builder = builder.Where(sq.Eq{"resource_id": *opts.ResourceID})
+	}
+
+	if opts.From != nil {
+		builder = builder.Where(sq.GtOrEq{"created_at": *opts.From})
+	}
+
+	if opts.To != nil {
+		builder = builder.Where(sq.Lt{"created_at": *opts.To})
+	}
+
+	if enablePagination && opts.Page != nil && opts.PerPage != nil {
+		builder = builder.Offset((uint64)(*opts.PerPage * (*opts.Page - 1))).Limit(uint64(*opts.PerPage))
+	}
+	return builder
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testEM/internal/entities"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const defaultPageSize = 20
+
+// sortKey is a non-nullable expression a listing is ordered by.
+type sortKey struct {
+	Field string
+	Expr  string
+	Desc  bool
+}
+
+// cursor holds values of the sort keys of the row next to which a keyset
+// page starts. It is passed to clients as an opaque base64 string.
+type cursor struct {
+	Keys     []string `json:"k"`
+	Backward bool     `json:"b,omitempty"`
+}
+
+func encodeCursor(c cursor) *string {
+	b, _ := json.Marshal(c)
+	s := base64.RawURLEncoding.EncodeToString(b)
+	return &s
+}
+
+// decodeCursor parses the cursor and checks that it was issued for a listing
+// with the same number of sort keys.
+func decodeCursor(s *string, keys int) (*cursor, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(*s)
+	if err != nil {
+		return nil, entities.NewValidationError("cursor", "is invalid")
+	}
+	c := cursor{}
+	if err = json.Unmarshal(b, &c); err != nil || len(c.Keys) != keys {
+		return nil, entities.NewValidationError("cursor", "is invalid")
+	}
+	return &c, nil
+}
+
+// isKeyset reports whether the listing should be paged by cursor rather
+// than by page number, which is kept for existing clients.
+func isKeyset(page *int, cur *string, perPage *int) bool {
+	return page == nil && (cur != nil || perPage != nil)
+}
+
+func pageSize(perPage *int) int {
+	if perPage == nil {
+		return defaultPageSize
+	}
+	return *perPage
+}
+
+func orderBy(builder sq.SelectBuilder, keys []sortKey, backward bool) sq.SelectBuilder {
+	for _, k := range keys {
+		if k.Desc != backward {
+			builder = builder.OrderBy(k.Expr + " DESC")
+		} else {
+			builder = builder.OrderBy(k.Expr + " ASC")
+		}
+	}
+	return builder
+}
+
+// applyKeyset restricts the query to rows after (or before, when paging
+// backward) the cursor in order of keys, the last of which must be unique.
+// One extra row is requested to find out whether there is a following page.
+func applyKeyset(builder sq.SelectBuilder, keys []sortKey, c *cursor, limit int) sq.SelectBuilder {
+	backward := c != nil && c.Backward
+	if c != nil {
+		// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., with the comparison
+		// flipped for descending keys and for backward paging
+		cond := sq.Or{}
+		for i, k := range keys {
+			and := sq.And{}
+			for j := 0; j < i; j++ {
+				and = append(and, sq.Expr(keys[j].Expr+" = ?", c.Keys[j]))
+			}
+			op := " > ?"
+			if k.Desc != backward {
+				op = " < ?"
+			}
+			and = append(and, sq.Expr(k.Expr+op, c.Keys[i]))
+			cond = append(cond, and)
+		}
+		builder = builder.Where(cond)
+	}
+	return orderBy(builder, keys, backward).Limit(uint64(limit + 1))
+}
+
+// buildPage trims the extra row requested by applyKeyset, restores the
+// requested order for backward pages and computes cursors of the
+// neighbouring pages.
+func buildPage[T any](items []T, c *cursor, limit int, keys func(T) []string) ([]T, entities.Page) {
+	page := entities.Page{}
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+
+	backward := c != nil && c.Backward
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	if len(items) == 0 {
+		return items, page
+	}
+
+	if hasMore || backward {
+		page.NextCursor = encodeCursor(cursor{Keys: keys(items[len(items)-1])})
+	}
+	if (hasMore && backward) || (c != nil && !backward) {
+		page.PrevCursor = encodeCursor(cursor{Keys: keys(items[0]), Backward: true})
+	}
+	return items, page
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testEM/internal/entities"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+type EnrichmentStorage struct {
+	db  Querier
+	log *zap.Logger
+}
+
+func NewEnrichmentStorage(db Querier, log *zap.Logger) *EnrichmentStorage {
+	return &EnrichmentStorage{
+		db:  db,
+		log: log,
+	}
+}
+
+func (st *EnrichmentStorage) EnqueueJob(ctx context.Context, songId string, splitter *string) error {
+	builder := sq.Insert("enrichment_jobs").
+		Columns("song_id", "splitter").
+		Values(songId, splitter).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to enqueue enrichment job",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	_, err = st.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in EnqueueJob",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+	return err
+}
+
+// ClaimJob leases the oldest due job to the caller. The lease protects the job
+// from other workers and replicas; when it expires without the job being
+// completed or rescheduled the job is handed out again. Returns nil when
+// there is nothing to do.
+func (st *EnrichmentStorage) ClaimJob(ctx context.Context, lease time.Duration) (*entities.EnrichmentJob, error) {
+	builder := sq.Update("enrichment_jobs").
+		Set("locked_until", sq.Expr("now() + ?::interval", fmt.Sprintf("%d milliseconds", lease.Milliseconds()))).
+		Where("id = (SELECT id FROM enrichment_jobs WHERE run_at <= now() AND " +
+			"(locked_until IS NULL OR locked_until < now()) ORDER BY run_at, id LIMIT 1 FOR UPDATE SKIP LOCKED)").
+		Suffix("RETURNING id, song_id, attempts, splitter").
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to claim enrichment job",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	job := entities.EnrichmentJob{}
+	err = st.db.QueryRowContext(ctx, query, args...).Scan(&job.ID, &job.SongID, &job.Attempts, &job.Splitter)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		st.log.Debug("Failed to execute query in ClaimJob",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+	return &job, err
+}
+
+func (st *EnrichmentStorage) CompleteJob(ctx context.Context, id string) error {
+	builder := sq.Delete("enrichment_jobs").Where(sq.Eq{"id": id}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to complete enrichment job",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	_, err = st.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in CompleteJob",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+	return err
+}
+
+// RetryJob releases the lease and schedules the job for another attempt.
+func (st *EnrichmentStorage) RetryJob(ctx context.Context, id string, runAt time.Time, lastErr string) error {
+	builder := sq.Update("enrichment_jobs").
+		Set("attempts", sq.Expr("attempts + 1")).
+		Set("run_at", runAt).
+		Set("locked_until", nil).
+		Set("last_error", lastErr).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to retry enrichment job",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	_, err = st.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in RetryJob",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+	return err
+}
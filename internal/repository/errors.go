@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"testEM/internal/entities"
+
+	"github.com/lib/pq"
+)
+
+// mapError translates PostgreSQL errors into domain errors. Resource and id
+// describe the row the failed statement was addressed to.
+func mapError(err error, resource, id string) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code {
+	case "23505":
+		return &entities.ConflictError{Resource: resource, Message: pqErr.Detail}
+	case "23503":
+		if strings.Contains(pqErr.Detail, "is still referenced") {
+			return &entities.ConflictError{Resource: resource, Message: pqErr.Detail}
+		}
+		return &entities.NotFoundError{Resource: resource, ID: id}
+	case "22P02":
+		// ids are passed as text, a malformed one can't match any row
+		return &entities.NotFoundError{Resource: resource, ID: id}
+	default:
+		return err
+	}
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testEM/internal/entities"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+type GroupStorage struct {
+	db  Querier
+	log *zap.Logger
+}
+
+func NewGroupStorage(db Querier, log *zap.Logger) *GroupStorage {
+	return &GroupStorage{
+		db:  db,
+		log: log,
+	}
+}
+
+func (st *GroupStorage) AddGroup(ctx context.Context, name string) (*entities.Group, error) {
+	builder := sq.Insert("groups").
+		Columns("name").
+		Values(name).
+		Suffix("RETURNING id, name").
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to add group",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	g := entities.Group{}
+	err = st.db.QueryRowContext(ctx, query, args...).Scan(&g.ID, &g.Name)
+	if err != nil {
+		st.log.Debug("Failed to execute query in AddGroup",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "group", "")
+	}
+	return &g, err
+}
+
+// EnsureGroup returns the group with the same name ignoring case,
+// creating it when there is none.
+func (st *GroupStorage) EnsureGroup(ctx context.Context, name string) (*entities.Group, error) {
+	builder := sq.Insert("groups").
+		Columns("name").
+		Values(name).
+		Suffix("ON CONFLICT ((lower(name))) DO UPDATE SET name = groups.name RETURNING id, name").
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to ensure group",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	g := entities.Group{}
+	err = st.db.QueryRowContext(ctx, query, args...).Scan(&g.ID, &g.Name)
+	if err != nil {
+		st.log.Debug("Failed to execute query in EnsureGroup",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "group", "")
+	}
+	return &g, err
+}
+
+func (st *GroupStorage) GetGroup(ctx context.Context, id string) (*entities.Group, error) {
+	builder := sq.Select("id", "name").From("groups").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get group",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	g := entities.Group{}
+	err = st.db.QueryRowContext(ctx, query, args...).Scan(&g.ID, &g.Name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &entities.NotFoundError{Resource: "group", ID: id}
+		}
+
+		st.log.Debug("Failed to execute query in GetGroup",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "group", id)
+	}
+	return &g, err
+}
+
+func (st *GroupStorage) GetGroups(ctx context.Context, opts *entities.GroupSearchOptions) ([]*entities.Group, entities.Page, error) {
+	keyset := isKeyset(opts.Page, opts.Cursor, opts.PerPage)
+	keys := []sortKey{{Field: "id", Expr: "id"}}
+	cur, err := decodeCursor(opts.Cursor, len(keys))
+	if err != nil {
+		return nil, entities.Page{}, err
+	}
+
+	builder := sq.Select("id", "name").From("groups")
+	builder = st.AddSearchOptionsToBuilder(builder, opts, true)
+	if keyset {
+		builder = applyKeyset(builder, keys, cur, pageSize(opts.PerPage))
+	} else {
+		builder = orderBy(builder, keys, false)
+	}
+	builder = builder.PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get groups",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, entities.Page{}, err
+	}
+
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in GetGroups",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, entities.Page{}, err
+	}
+	defer rows.Close()
+
+	groups := make([]*entities.Group, 0)
+	for rows.Next() {
+		g := entities.Group{}
+		if err := rows.Scan(&g.ID, &g.Name); err != nil {
+			st.log.Debug("Failed to scan row in GetGroups")
+			return nil, entities.Page{}, err
+		}
+		groups = append(groups, &g)
+	}
+	if err = rows.Err(); err != nil {
+		st.log.Debug("Failed to scan rows in GetGroups")
+		return nil, entities.Page{}, err
+	}
+
+	page := entities.Page{}
+	if keyset {
+		groups, page = buildPage(groups, cur, pageSize(opts.PerPage), func(g *entities.Group) []string {
+			return []string{*g.ID}
+		})
+		if opts.WithTotal == nil || !*opts.WithTotal {
+			return groups, page, err
+		}
+	}
+
+	builder = sq.Select("count(*)").From("groups")
+	builder = st.AddSearchOptionsToBuilder(builder, opts, false)
+	builder = builder.PlaceholderFormat(sq.Dollar)
+
+	query, args, err = builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get total groups",
+			zap.String("message", err.Error()),
+		)
+		return nil, entities.Page{}, err
+	}
+
+	var count int
+	err = st.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		st.log.Debug("Failed to execute query for total groups in GetGroups",
+			zap.String("message", err.Error()),
+		)
+		return nil, entities.Page{}, err
+	}
+	page.Total = &count
+
+	return groups, page, err
+}
+
+func (st *GroupStorage) RenameGroup(ctx context.Context, id string, name string) (*entities.Group, error) {
+	builder := sq.Update("groups").
+		Set("name", name).
+		Where(sq.Eq{"id": id}).
+		Suffix("RETURNING id, name").
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to rename group",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	g := entities.Group{}
+	err = st.db.QueryRowContext(ctx, query, args...).Scan(&g.ID, &g.Name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &entities.NotFoundError{Resource: "group", ID: id}
+		}
+
+		st.log.Debug("Failed to execute query in RenameGroup",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "group", id)
+	}
+	return &g, err
+}
+
+func (st *GroupStorage) DeleteGroup(ctx context.Context, id string) error {
+	builder := sq.Delete("groups").Where(sq.Eq{"id": id}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to delete group",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	res, err := st.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in DeleteGroup",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return mapError(err, "group", id)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return &entities.NotFoundError{Resource: "group", ID: id}
+	}
+	return err
+}
+
+func (st *GroupStorage) AddSearchOptionsToBuilder(builder sq.SelectBuilder, opts *entities.GroupSearchOptions, enablePagination bool) sq.SelectBuilder {
+	if opts.Name != nil {
+		builder = builder.Where(sq.Expr("lower(name) LIKE ?", "%"+likeEscaper.Replace(strings.ToLower(*opts.Name))+"%"))
+	}
+
+	if enablePagination && opts.Page != nil && opts.PerPage != nil {
+		builder = builder.Offset((uint64)(*opts.PerPage * (*opts.Page - 1))).Limit(uint64(*opts.PerPage))
+	}
+	return builder
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"testEM/internal/entities"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+type LineStorage struct {
+	db  Querier
+	log *zap.Logger
+}
+
+func NewLineStorage(db Querier, log *zap.Logger) *LineStorage {
+	return &LineStorage{
+		db:  db,
+		log: log,
+	}
+}
+
+func scanLine(row scanner) (*entities.Line, error) {
+	l := entities.Line{}
+	if err := row.Scan(&l.VerseNumber, &l.Number, &l.Content, &l.StartMs, &l.EndMs); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// ReplaceSongLines drops all lines of the song and adds the given ones,
+// lines are bound to verses by their current numbers.
+func (st *LineStorage) ReplaceSongLines(ctx context.Context, songId string, lines []*entities.Line) error {
+	if err := st.DeleteSongLines(ctx, songId); err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	builder := sq.Insert("lines").Columns("verse_id", "num", "content", "start_ms", "end_ms")
+	for _, l := range lines {
+		builder = builder.Values(
+			sq.Expr("(SELECT id FROM verses WHERE song_id = ? AND num = ?)", songId, l.VerseNumber),
+			l.Number, l.Content, l.StartMs, l.EndMs,
+		)
+	}
+
+	builder = builder.PlaceholderFormat(sq.Dollar)
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to add lines",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	_, err = st.db.ExecContext(ctx, queryStr, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in ReplaceSongLines",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+	return mapError(err, "song", songId)
+}
+
+func (st *LineStorage) DeleteSongLines(ctx context.Context, songId string) error {
+	builder := sq.Delete("lines").
+		Where("verse_id IN (SELECT id FROM verses WHERE song_id = ?)", songId).
+		PlaceholderFormat(sq.Dollar)
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to delete lines",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	_, err = st.db.ExecContext(ctx, queryStr, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in DeleteSongLines",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+	return mapError(err, "song", songId)
+}
+
+func (st *LineStorage) DeleteVerseLines(ctx context.Context, songId string, num int) error {
+	builder := sq.Delete("lines").
+		Where("verse_id IN (SELECT id FROM verses WHERE song_id = ? AND num = ?)", songId, num).
+		PlaceholderFormat(sq.Dollar)
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to delete verse lines",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	_, err = st.db.ExecContext(ctx, queryStr, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in DeleteVerseLines",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+	return mapError(err, "song", songId)
+}
+
+func (st *LineStorage) GetSongLines(ctx context.Context, songId string) ([]*entities.Line, error) {
+	builder := sq.Select("v.num", "l.num", "l.content", "l.start_ms", "l.end_ms").
+		From("lines l").
+		Join("verses v ON v.id = l.verse_id").
+		Where(sq.Eq{"v.song_id": songId}).
+		OrderBy("v.num", "l.num").
+		PlaceholderFormat(sq.Dollar)
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get lines",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	rows, err := st.db.QueryContext(ctx, queryStr, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in GetSongLines",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "song", songId)
+	}
+	defer rows.Close()
+
+	lines := make([]*entities.Line, 0)
+	for rows.Next() {
+		l, err := scanLine(rows)
+		if err != nil {
+			st.log.Debug("Failed to scan row in GetSongLines")
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+
+	if err = rows.Err(); err != nil {
+		st.log.Debug("Failed to scan rows in GetSongLines")
+		return nil, err
+	}
+	return lines, err
+}
+
+// GetActiveLine returns the last line started at or before the offset,
+// unless it has already ended by then.
+func (st *LineStorage) GetActiveLine(ctx context.Context, songId string, offsetMs int) (*entities.Line, error) {
+	builder := sq.Select("v.num", "l.num", "l.content", "l.start_ms", "l.end_ms").
+		From("lines l").
+		Join("verses v ON v.id = l.verse_id").
+		Where(sq.Eq{"v.song_id": songId}).
+		Where(songNotDeleted("v.song_id")).
+		Where(sq.LtOrEq{"l.start_ms": offsetMs}).
+		OrderBy("l.start_ms DESC", "v.num DESC", "l.num DESC").
+		Limit(1).
+		PlaceholderFormat(sq.Dollar)
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get active line",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	l, err := scanLine(st.db.QueryRowContext(ctx, queryStr, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &entities.NotFoundError{Resource: "line at offset", ID: strconv.Itoa(offsetMs)}
+		}
+
+		st.log.Debug("Failed to execute query in GetActiveLine",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "song", songId)
+	}
+	if l.EndMs != nil && *l.EndMs <= offsetMs {
+		return nil, &entities.NotFoundError{Resource: "line at offset", ID: strconv.Itoa(offsetMs)}
+	}
+	return l, err
+}
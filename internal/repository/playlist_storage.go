@@ -0,0 +1,460 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"testEM/internal/entities"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+var playlistColumns = []string{"id", "name", "description"}
+
+type PlaylistStorage struct {
+	db  Querier
+	log *zap.Logger
+}
+
+func NewPlaylistStorage(db Querier, log *zap.Logger) *PlaylistStorage {
+	return &PlaylistStorage{
+		db:  db,
+		log: log,
+	}
+}
+
+func scanPlaylist(row scanner) (*entities.Playlist, error) {
+	p := entities.Playlist{}
+	if err := row.Scan(&p.ID, &p.Name, &p.Description); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (st *PlaylistStorage) AddPlaylist(ctx context.Context, playlist entities.Playlist) (*entities.Playlist, error) {
+	builder := sq.Insert("playlists").
+		Columns("name", "description").
+		Values(playlist.Name, playlist.Description).
+		Suffix("RETURNING " + strings.Join(playlistColumns, ", ")).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to add playlist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	p, err := scanPlaylist(st.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		st.log.Debug("Failed to execute query in AddPlaylist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+	return p, err
+}
+
+func (st *PlaylistStorage) GetPlaylist(ctx context.Context, id string) (*entities.Playlist, error) {
+	builder := sq.Select(playlistColumns...).From("playlists").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get playlist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	p, err := scanPlaylist(st.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &entities.NotFoundError{Resource: "playlist", ID: id}
+		}
+
+		st.log.Debug("Failed to execute query in GetPlaylist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "playlist", id)
+	}
+	return p, err
+}
+
+func (st *PlaylistStorage) GetPlaylists(ctx context.Context, opts *entities.PlaylistSearchOptions) ([]*entities.Playlist, entities.Page, error) {
+	keyset := isKeyset(opts.Page, opts.Cursor, opts.PerPage)
+	keys := []sortKey{{Field: "id", Expr: "id"}}
+	cur, err := decodeCursor(opts.Cursor, len(keys))
+	if err != nil {
+		return nil, entities.Page{}, err
+	}
+
+	builder := sq.Select(playlistColumns...).From("playlists")
+	builder = st.AddSearchOptionsToBuilder(builder, opts, true)
+	if keyset {
+		builder = applyKeyset(builder, keys, cur, pageSize(opts.PerPage))
+	} else {
+		builder = orderBy(builder, keys, false)
+	}
+	builder = builder.PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get playlists",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, entities.Page{}, err
+	}
+
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in GetPlaylists",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, entities.Page{}, err
+	}
+	defer rows.Close()
+
+	playlists := make([]*entities.Playlist, 0)
+	for rows.Next() {
+		p, err := scanPlaylist(rows)
+		if err != nil {
+			st.log.Debug("Failed to scan row in GetPlaylists")
+			return nil, entities.Page{}, err
+		}
+		playlists = append(playlists, p)
+	}
+	if err = rows.Err(); err != nil {
+		st.log.Debug("Failed to scan rows in GetPlaylists")
+		return nil, entities.Page{}, err
+	}
+
+	page := entities.Page{}
+	if keyset {
+		playlists, page = buildPage(playlists, cur, pageSize(opts.PerPage), func(p *entities.Playlist) []string {
+			return []string{*p.ID}
+		})
+		if opts.WithTotal == nil || !*opts.WithTotal {
+			return playlists, page, err
+		}
+	}
+
+	builder = sq.Select("count(*)").From("playlists")
+	builder = st.AddSearchOptionsToBuilder(builder, opts, false)
+	builder = builder.PlaceholderFormat(sq.Dollar)
+
+	query, args, err = builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get total playlists",
+			zap.String("message", err.Error()),
+		)
+		return nil, entities.Page{}, err
+	}
+
+	var count int
+	err = st.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		st.log.Debug("Failed to execute query for total playlists in GetPlaylists",
+			zap.String("message", err.Error()),
+		)
+		return nil, entities.Page{}, err
+	}
+	page.Total = &count
+
+	return playlists, page, err
+}
+
+func (st *PlaylistStorage) UpdatePlaylist(ctx context.Context, id string, playlist entities.Playlist) (*entities.Playlist, error) {
+	if playlist.Name == nil && playlist.Description == nil {
+		return st.GetPlaylist(ctx, id)
+	}
+
+	builder := sq.Update("playlists").Where(sq.Eq{"id": id})
+	if playlist.Name != nil {
+		builder = builder.Set("name", playlist.Name)
+	}
+	if playlist.Description != nil {
+		builder = builder.Set("description", playlist.Description)
+	}
+	builder = builder.Suffix("RETURNING " + strings.Join(playlistColumns, ", ")).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to update playlist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	p, err := scanPlaylist(st.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &entities.NotFoundError{Resource: "playlist", ID: id}
+		}
+
+		st.log.Debug("Failed to execute query in UpdatePlaylist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "playlist", id)
+	}
+	return p, err
+}
+
+func (st *PlaylistStorage) DeletePlaylist(ctx context.Context, id string) error {
+	builder := sq.Delete("playlists").Where(sq.Eq{"id": id}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to delete playlist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	res, err := st.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in DeletePlaylist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return mapError(err, "playlist", id)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return &entities.NotFoundError{Resource: "playlist", ID: id}
+	}
+	return err
+}
+
+// GetPlaylistSongs returns entries of the playlist ordered by position.
+func (st *PlaylistStorage) GetPlaylistSongs(ctx context.Context, playlistId string) ([]*entities.PlaylistSong, error) {
+	builder := sq.Select(append([]string{"p.num"}, qualify("songs", songColumns)...)...).
+		From("playlist_songs p").
+		Join("songs ON songs.id = p.song_id").
+		Where(sq.Eq{"p.playlist_id": playlistId}).
+		Where("songs.deleted_at IS NULL").
+		OrderBy("p.num").
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get playlist songs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in GetPlaylistSongs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "playlist", playlistId)
+	}
+	defer rows.Close()
+
+	songs := make([]*entities.PlaylistSong, 0)
+	for rows.Next() {
+		ps := entities.PlaylistSong{Song: &entities.Song{}}
+		if err := rows.Scan(append([]any{&ps.Number}, songFields(ps.Song)...)...); err != nil {
+			st.log.Debug("Failed to scan row in GetPlaylistSongs")
+			return nil, err
+		}
+		songs = append(songs, &ps)
+	}
+	if err = rows.Err(); err != nil {
+		st.log.Debug("Failed to scan rows in GetPlaylistSongs")
+		return nil, err
+	}
+	return songs, err
+}
+
+func (st *PlaylistStorage) CountPlaylistSongs(ctx context.Context, playlistId string) (int, error) {
+	builder := sq.Select("count(*)").From("playlist_songs").
+		Where(sq.Eq{"playlist_id": playlistId}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to count playlist songs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return 0, err
+	}
+
+	var count int
+	err = st.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		st.log.Debug("Failed to execute query in CountPlaylistSongs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return 0, mapError(err, "playlist", playlistId)
+	}
+	return count, err
+}
+
+// InsertPlaylistSong puts the song at the given position, following entries
+// are shifted down within the same statement.
+func (st *PlaylistStorage) InsertPlaylistSong(ctx context.Context, playlistId string, num int, songId string) error {
+	builder := sq.Insert("playlist_songs").
+		Prefix("WITH shifted AS (UPDATE playlist_songs SET num = num + 1 WHERE playlist_id = ? AND num >= ?)", playlistId, num).
+		Columns("playlist_id", "num", "song_id").
+		Values(playlistId, num, songId).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to insert playlist song",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	_, err = st.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in InsertPlaylistSong",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return mapError(err, "song", songId)
+	}
+	return err
+}
+
+// RemovePlaylistSong removes the entry at the given position and closes the gap.
+func (st *PlaylistStorage) RemovePlaylistSong(ctx context.Context, playlistId string, num int) error {
+	builder := sq.Select("count(*)").From("deleted").
+		Prefix("WITH deleted AS (DELETE FROM playlist_songs WHERE playlist_id = ? AND num = ? RETURNING num), "+
+			"shifted AS (UPDATE playlist_songs SET num = num - 1 WHERE playlist_id = ? AND num > ? AND EXISTS (SELECT 1 FROM deleted))",
+			playlistId, num, playlistId, num).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to remove playlist song",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	var deleted int
+	err = st.db.QueryRowContext(ctx, query, args...).Scan(&deleted)
+	if err != nil {
+		st.log.Debug("Failed to execute query in RemovePlaylistSong",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return mapError(err, "playlist", playlistId)
+	}
+	if deleted == 0 {
+		return &entities.NotFoundError{Resource: "playlist entry", ID: strconv.Itoa(num)}
+	}
+	return err
+}
+
+// MovePlaylistSong moves the entry from one position to another, shifting
+// the entries in between by one within a single statement.
+func (st *PlaylistStorage) MovePlaylistSong(ctx context.Context, playlistId string, from, to int) error {
+	lo, hi := from, to
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+
+	builder := sq.Update("playlist_songs").
+		Set("num", sq.Expr("CASE WHEN num = ? THEN ? WHEN ? < ? THEN num - 1 ELSE num + 1 END", from, to, from, to)).
+		Where(sq.Eq{"playlist_id": playlistId}).
+		Where(sq.Expr("num BETWEEN ? AND ?", lo, hi)).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to move playlist song",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	res, err := st.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in MovePlaylistSong",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return mapError(err, "playlist", playlistId)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return &entities.NotFoundError{Resource: "playlist entry", ID: strconv.Itoa(from)}
+	}
+	return err
+}
+
+// RemoveSongFromPlaylists removes every entry of the song and renumbers the
+// remaining entries of the affected playlists. Used before the song itself is deleted.
+func (st *PlaylistStorage) RemoveSongFromPlaylists(ctx context.Context, songId string) error {
+	builder := sq.Update("playlist_songs p").
+		Prefix("WITH deleted AS (DELETE FROM playlist_songs WHERE song_id = ? RETURNING playlist_id, num)", songId).
+		Set("num", sq.Expr("p.num - (SELECT count(*) FROM deleted d WHERE d.playlist_id = p.playlist_id AND d.num < p.num)")).
+		Where("p.playlist_id IN (SELECT playlist_id FROM deleted)").
+		Where(sq.NotEq{"p.song_id": songId}).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to remove song from playlists",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	_, err = st.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in RemoveSongFromPlaylists",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return mapError(err, "song", songId)
+	}
+	return err
+}
+
+func (st *PlaylistStorage) AddSearchOptionsToBuilder(builder sq.SelectBuilder, opts *entities.PlaylistSearchOptions, enablePagination bool) sq.SelectBuilder {
+	if opts.Name != nil {
+		builder = builder.Where(sq.Expr("lower(name) LIKE ?", "%"+likeEscaper.Replace(strings.ToLower(*opts.Name))+"%"))
+	}
+
+	if enablePagination && opts.Page != nil && opts.PerPage != nil {
+		builder = builder.Offset((uint64)(*opts.PerPage * (*opts.Page - 1))).Limit(uint64(*opts.PerPage))
+	}
+	return builder
+}
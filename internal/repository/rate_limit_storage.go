@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testEM/pkg/ratelimit"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+// rateLimitSweepInterval is how often full buckets are deleted.
+const rateLimitSweepInterval = time.Minute
+
+// RateLimitStorage keeps rate limit buckets in PostgreSQL, so they are shared
+// by all replicas. Tokens are taken by the rate_limit_take function which
+// locks the bucket row.
+type RateLimitStorage struct {
+	db  Querier
+	log *zap.Logger
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewRateLimitStorage(db Querier, log *zap.Logger) *RateLimitStorage {
+	return &RateLimitStorage{
+		db:        db,
+		log:       log,
+		lastSweep: time.Now(),
+	}
+}
+
+func (st *RateLimitStorage) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	st.sweep(ctx)
+
+	builder := sq.Select("remaining", "granted").
+		From("rate_limit_take(?, ?, ?)").
+		PlaceholderFormat(sq.Dollar)
+	query, _, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to take rate limit token",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return ratelimit.Result{}, err
+	}
+
+	var tokens float64
+	var allowed bool
+	err = st.db.QueryRowContext(ctx, query, key, limit.Rate, limit.Burst).Scan(&tokens, &allowed)
+	if err != nil {
+		st.log.Debug("Failed to execute query in Take",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return ratelimit.Result{}, err
+	}
+	return limit.Result(tokens, allowed), nil
+}
+
+// sweep deletes buckets that have filled up, at most once a minute.
+func (st *RateLimitStorage) sweep(ctx context.Context) {
+	st.mu.Lock()
+	if time.Since(st.lastSweep) < rateLimitSweepInterval {
+		st.mu.Unlock()
+		return
+	}
+	st.lastSweep = time.Now()
+	st.mu.Unlock()
+
+	query, args, err := sq.Delete("rate_limits").
+		Where("full_at < now()").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to sweep rate limits",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return
+	}
+
+	_, err = st.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in sweep",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+}
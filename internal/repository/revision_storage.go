@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testEM/internal/entities"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+type RevisionStorage struct {
+	db  Querier
+	log *zap.Logger
+}
+
+func NewRevisionStorage(db Querier, log *zap.Logger) *RevisionStorage {
+	return &RevisionStorage{
+		db:  db,
+		log: log,
+	}
+}
+
+var revisionColumns = []string{"song_id", "num", "action", "actor", "created_at", "diff"}
+
+// scanRevision reads revisionColumns, followed by snapshot when withSnapshot is set.
+func scanRevision(row scanner, withSnapshot bool) (*entities.Revision, error) {
+	rev := entities.Revision{}
+	var diff, snapshot []byte
+	dest := []any{&rev.SongID, &rev.Number, &rev.Action, &rev.Actor, &rev.CreatedAt, &diff}
+	if withSnapshot {
+		dest = append(dest, &snapshot)
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(diff, &rev.Diff); err != nil {
+		return nil, err
+	}
+	if snapshot != nil {
+		rev.Snapshot = &entities.SongSnapshot{}
+		if err := json.Unmarshal(snapshot, rev.Snapshot); err != nil {
+			return nil, err
+		}
+	}
+	return &rev, nil
+}
+
+// AddRevision stores the revision with the next number of the song.
+func (st *RevisionStorage) AddRevision(ctx context.Context, rev entities.Revision) (*entities.Revision, error) {
+	diff, err := json.Marshal(rev.Diff)
+	if err != nil {
+		return nil, err
+	}
+	// json is passed as text, lib/pq would send []byte as bytea
+	var snapshot *string
+	if rev.Snapshot != nil {
+		data, err := json.Marshal(rev.Snapshot)
+		if err != nil {
+			return nil, err
+		}
+		snapshot = new(string)
+		*snapshot = string(data)
+	}
+
+	builder := sq.Insert("song_revisions").
+		Columns("song_id", "num", "action", "actor", "diff", "snapshot").
+		Values(rev.SongID,
+			sq.Expr("(SELECT coalesce(max(num), 0) + 1 FROM song_revisions WHERE song_id = ?)", rev.SongID),
+			rev.Action, rev.Actor, string(diff), snapshot).
+		Suffix("RETURNING num, created_at").
+		PlaceholderFormat(sq.Dollar)
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to add revision",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	err = st.db.QueryRowContext(ctx, query, args...).Scan(&rev.Number, &rev.CreatedAt)
+	if err != nil {
+		st.log.Debug("Failed to execute query in AddRevision",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "song", rev.SongID)
+	}
+	return &rev, err
+}
+
+// GetLatestRevision returns the last revision of the song with its snapshot,
+// or nil when the song has no history.
+func (st *RevisionStorage) GetLatestRevision(ctx context.Context, songId string) (*entities.Revision, error) {
+	builder := sq.Select(append(revisionColumns, "snapshot")...).
+		From("song_revisions").
+		Where(sq.Eq{"song_id": songId}).
+		OrderBy("num DESC").
+		Limit(1).
+		PlaceholderFormat(sq.Dollar)
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get latest revision",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	rev, err := scanRevision(st.db.QueryRowContext(ctx, query, args...), true)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		st.log.Debug("Failed to execute query in GetLatestRevision",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "song", songId)
+	}
+	return rev, err
+}
+
+func (st *RevisionStorage) GetRevision(ctx context.Context, songId string, num int) (*entities.Revision, error) {
+	builder := sq.Select(append(revisionColumns, "snapshot")...).
+		From("song_revisions").
+		Where(sq.Eq{"song_id": songId, "num": num}).
+		PlaceholderFormat(sq.Dollar)
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get revision",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	rev, err := scanRevision(st.db.QueryRowContext(ctx, query, args...), true)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &entities.NotFoundError{Resource: "revision", ID: strconv.Itoa(num)}
+		}
+
+		st.log.Debug("Failed to execute query in GetRevision",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "song", songId)
+	}
+	return rev, err
+}
+
+// GetRevisions lists revisions of the song newest first, without snapshots.
+func (st *RevisionStorage) GetRevisions(ctx context.Context, opts *entities.RevisionSearchOptions) ([]*entities.Revision, int, error) {
+	builder := sq.Select(revisionColumns...).
+		From("song_revisions").
+		Where(sq.Eq{"song_id": *opts.SongID}).
+		OrderBy("num DESC")
+	if opts.Page != nil && opts.PerPage != nil {
+		builder = builder.Offset((uint64)(*opts.PerPage * (*opts.Page - 1))).Limit(uint64(*opts.PerPage))
+	}
+	builder = builder.PlaceholderFormat(sq.Dollar)
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get revisions",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, 0, err
+	}
+
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in GetRevisions",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, 0, mapError(err, "song", *opts.SongID)
+	}
+	defer rows.Close()
+
+	revisions := make([]*entities.Revision, 0)
+	for rows.Next() {
+		rev, err := scanRevision(rows, false)
+		if err != nil {
+			st.log.Debug("Failed to scan row in GetRevisions")
+			return nil, 0, err
+		}
+		revisions = append(revisions, rev)
+	}
+	if err = rows.Err(); err != nil {
+		st.log.Debug("Failed to scan rows in GetRevisions")
+		return nil, 0, err
+	}
+
+	countBuilder := sq.Select("count(*)").
+		From("song_revisions").
+		Where(sq.Eq{"song_id": *opts.SongID}).
+		PlaceholderFormat(sq.Dollar)
+	query, args, err = countBuilder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to count revisions",
+			zap.String("message", err.Error()),
+		)
+		return nil, 0, err
+	}
+
+	var count int
+	err = st.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		st.log.Debug("Failed to execute query to count revisions",
+			zap.String("message", err.Error()),
+		)
+		return nil, 0, err
+	}
+	return revisions, count, err
+}
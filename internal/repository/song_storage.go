@@ -1,42 +1,70 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"testEM/internal/entities"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
 type SongStorage struct {
-	db  *sql.DB
+	db  Querier
 	log *zap.Logger
 }
 
-func NewSongStorage(db *sql.DB, log *zap.Logger) *SongStorage {
+func NewSongStorage(db Querier, log *zap.Logger) *SongStorage {
 	return &SongStorage{
 		db:  db,
 		log: log,
 	}
 }
 
-type NotFoundErr struct {
-	s string
+var songColumns = []string{"id", "group_name", "group_id", "song", "release_date", "link", "enrichment_status", "deleted_at", "version"}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// songFields returns scan destinations matching songColumns.
+func songFields(s *entities.Song) []any {
+	return []any{&s.ID, &s.Group, &s.GroupID, &s.Song, &s.ReleaseDate, &s.Link, &s.EnrichmentStatus, &s.DeletedAt, &s.Version}
 }
 
-func (e *NotFoundErr) Error() string {
-	e.s = "not found"
-	return e.s
+// qualify prefixes every column with the table alias.
+func qualify(alias string, columns []string) []string {
+	res := make([]string, len(columns))
+	for i, c := range columns {
+		res[i] = alias + "." + c
+	}
+	return res
 }
 
-func (st *SongStorage) AddSong(song entities.Song) (*entities.Song, error) {
+// songNotDeleted is a condition on the song referenced by column, hiding
+// rows of deleted songs.
+func songNotDeleted(column string) string {
+	return "EXISTS (SELECT 1 FROM songs s WHERE s.id = " + column + " AND s.deleted_at IS NULL)"
+}
+
+func scanSong(row scanner) (*entities.Song, error) {
+	s := entities.Song{}
+	if err := row.Scan(songFields(&s)...); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (st *SongStorage) AddSong(ctx context.Context, song entities.Song) (*entities.Song, error) {
 	builder := sq.Insert("songs").
-		Columns("group_name", "song", "release_date", "link").
-		Values(*song.Group, *song.Song, *song.ReleaseDate, *song.Link).
-		Suffix("RETURNING \"id\"").PlaceholderFormat(sq.Dollar)
+		Columns("group_name", "group_id", "song", "release_date", "link", "enrichment_status").
+		Values(song.Group, song.GroupID, song.Song, song.ReleaseDate, song.Link, song.EnrichmentStatus).
+		Suffix("RETURNING " + strings.Join(songColumns, ", ")).PlaceholderFormat(sq.Dollar)
 
 	query, args, err := builder.ToSql()
 	if err != nil {
@@ -47,21 +75,114 @@ func (st *SongStorage) AddSong(song entities.Song) (*entities.Song, error) {
 		return nil, err
 	}
 
-	err = st.db.QueryRow(query, args...).Scan(&song.ID)
+	s, err := scanSong(st.db.QueryRowContext(ctx, query, args...))
 	if err != nil {
 		st.log.Debug("Failed to execute query in AddSong",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
+		return nil, mapError(err, "song", "")
+	}
+
+	return s, err
+}
+
+// UpsertSong writes all fields of the song, recreating it with the same id
+// when it was purged and bringing it back when it was deleted.
+func (st *SongStorage) UpsertSong(ctx context.Context, song entities.Song) (*entities.Song, error) {
+	builder := sq.Insert("songs").
+		Columns("id", "group_name", "group_id", "song", "release_date", "link", "enrichment_status").
+		Values(song.ID, song.Group, song.GroupID, song.Song, song.ReleaseDate, song.Link, song.EnrichmentStatus).
+		Suffix("ON CONFLICT (id) DO UPDATE SET group_name = EXCLUDED.group_name, group_id = EXCLUDED.group_id, " +
+			"song = EXCLUDED.song, release_date = EXCLUDED.release_date, link = EXCLUDED.link, " +
+			"enrichment_status = EXCLUDED.enrichment_status, deleted_at = NULL, version = songs.version + 1 " +
+			"RETURNING " + strings.Join(songColumns, ", ")).
+		PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to upsert song",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	s, err := scanSong(st.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		st.log.Debug("Failed to execute query in UpsertSong",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "song", *song.ID)
+	}
+	return s, err
+}
+
+// AddSongs inserts the songs with a single statement, returned songs
+// follow the order of the input.
+func (st *SongStorage) AddSongs(ctx context.Context, songs []entities.Song) ([]*entities.Song, error) {
+	if len(songs) == 0 {
+		return nil, nil
+	}
+
+	builder := sq.Insert("songs").
+		Columns("group_name", "group_id", "song", "release_date", "link", "enrichment_status")
+	for _, song := range songs {
+		builder = builder.Values(song.Group, song.GroupID, song.Song, song.ReleaseDate, song.Link, song.EnrichmentStatus)
+	}
+	builder = builder.Suffix("RETURNING " + strings.Join(songColumns, ", ")).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to add songs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
 		return nil, err
 	}
 
-	return &song, err
+	rows, err := st.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in AddSongs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "song", "")
+	}
+	defer rows.Close()
+
+	res := make([]*entities.Song, 0, len(songs))
+	for rows.Next() {
+		s, err := scanSong(rows)
+		if err != nil {
+			st.log.Debug("Failed to scan row in AddSongs")
+			return nil, err
+		}
+		res = append(res, s)
+	}
+	if err = rows.Err(); err != nil {
+		st.log.Debug("Failed to scan rows in AddSongs")
+		return nil, mapError(err, "song", "")
+	}
+	return res, err
 }
 
-func (st *SongStorage) GetSongsWithFilters(opts *entities.SongSearchOptions) ([]*entities.Song, int, error) {
-	builder := sq.Select("*").From("songs")
+func (st *SongStorage) GetSongsWithFilters(ctx context.Context, opts *entities.SongSearchOptions) ([]*entities.Song, entities.Page, error) {
+	keyset := isKeyset(opts.Page, opts.Cursor, opts.PerPage)
+	keys := songSortKeys(opts.Sort)
+	cur, err := decodeCursor(opts.Cursor, len(keys))
+	if err != nil {
+		return nil, entities.Page{}, err
+	}
+
+	builder := sq.Select(songColumns...).From("songs")
 	builder = st.AddSearchOptionsToBuilder(builder, opts, true)
+	if keyset {
+		builder = applyKeyset(builder, keys, cur, pageSize(opts.PerPage))
+	} else {
+		builder = orderBy(builder, keys, false)
+	}
 	builder = builder.PlaceholderFormat(sq.Dollar)
 
 	queryStr, args, err := builder.ToSql()
@@ -70,58 +191,257 @@ func (st *SongStorage) GetSongsWithFilters(opts *entities.SongSearchOptions) ([]
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		return nil, 0, err
+		return nil, entities.Page{}, err
 	}
 
 	songs := make([]*entities.Song, 0)
-	rows, err := st.db.Query(queryStr, args...)
+	rows, err := st.db.QueryContext(ctx, queryStr, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in GetSongsWithFilters",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, entities.Page{}, err
+	}
+	defer rows.Close()
 
 	for rows.Next() {
-		s := entities.Song{}
-		if err := rows.Scan(&s.ID, &s.Group, &s.Song, &s.ReleaseDate, &s.Link); err != nil {
+		s, err := scanSong(rows)
+		if err != nil {
 			st.log.Debug("Failed to scan row in GetSongsWithFilters")
-			return nil, 0, err
+			return nil, entities.Page{}, err
 		}
-		songs = append(songs, &s)
+		songs = append(songs, s)
 	}
 	if err = rows.Err(); err != nil {
 		st.log.Debug("Failed to scan rows in GetSongsWithFilters")
-		return nil, 0, err
+		return nil, entities.Page{}, err
 	}
-	defer rows.Close()
 
+	page := entities.Page{}
+	if keyset {
+		songs, page = buildPage(songs, cur, pageSize(opts.PerPage), func(s *entities.Song) []string {
+			values := make([]string, len(keys))
+			for i, k := range keys {
+				values[i] = songSortValue(s, k.Field)
+			}
+			return values
+		})
+		if opts.WithTotal == nil || !*opts.WithTotal {
+			return songs, page, err
+		}
+	}
+
+	builder = sq.Select("count(*)").From("songs")
+	builder = st.AddSearchOptionsToBuilder(builder, opts, false)
+	builder = builder.PlaceholderFormat(sq.Dollar)
+
+	queryStr, args, err = builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get total songs with filters",
+			zap.String("message", err.Error()),
+		)
+		return nil, entities.Page{}, err
+	}
+
+	var count int
+	err = st.db.QueryRowContext(ctx, queryStr, args...).Scan(&count)
+	if err != nil {
+		st.log.Debug("Failed to execute query for total songs in GetSongsWithFilters",
+			zap.String("message", err.Error()),
+		)
+		return nil, entities.Page{}, err
+	}
+	page.Total = &count
+
+	return songs, page, err
+}
+
+func (st *SongStorage) GetSong(ctx context.Context, id string) (*entities.Song, error) {
+	builder := sq.Select(songColumns...).From("songs").
+		Where(sq.Eq{"id": id}).
+		Where("deleted_at IS NULL").
+		PlaceholderFormat(sq.Dollar)
+
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get song",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	s, err := scanSong(st.db.QueryRowContext(ctx, queryStr, args...))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, 0, &NotFoundErr{}
+			return nil, &entities.NotFoundError{Resource: "song", ID: id}
 		}
 
-		st.log.Debug("Failed to execute query in GetSongsWithFilters",
+		st.log.Debug("Failed to execute query in GetSong",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		return nil, 0, err
+		return nil, mapError(err, "song", id)
 	}
+	return s, err
+}
 
-	builder = sq.Select("count(*)").From("songs")
+// LockSong locks the row of the song, deleted or not, until the end of the
+// transaction so that changes of the song are numbered one after another.
+func (st *SongStorage) LockSong(ctx context.Context, id string) error {
+	builder := sq.Select("id").From("songs").
+		Where(sq.Eq{"id": id}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar)
+
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to lock song",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	var locked string
+	err = st.db.QueryRowContext(ctx, queryStr, args...).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &entities.NotFoundError{Resource: "song", ID: id}
+		}
+
+		st.log.Debug("Failed to execute query in LockSong",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return mapError(err, "song", id)
+	}
+	return nil
+}
+
+func (st *SongStorage) SearchSongsByLyrics(ctx context.Context, opts *entities.SongSearchOptions) ([]*entities.Song, int, error) {
+	builder := sq.Select(qualify("songs", songColumns)...).
+		Column(sq.Expr("max(ts_rank(to_tsvector('simple', v.content), websearch_to_tsquery('simple', ?))) AS rank", *opts.Lyrics)).
+		From("songs").
+		Join("verse_texts v ON v.song_id = songs.id").
+		Where(sq.Expr("to_tsvector('simple', v.content) @@ websearch_to_tsquery('simple', ?)", *opts.Lyrics)).
+		GroupBy("songs.id").
+		OrderBy("rank DESC")
+	builder = orderBy(builder, songSortKeys(opts.Sort), false)
 	builder = st.AddSearchOptionsToBuilder(builder, opts, false)
+	// results are always paged, the first page of the default size when not asked
+	page := 1
+	if opts.Page != nil {
+		page = *opts.Page
+	}
+	size := pageSize(opts.PerPage)
+	builder = builder.Offset(uint64(size * (page - 1))).Limit(uint64(size))
 	builder = builder.PlaceholderFormat(sq.Dollar)
 
-	queryStr, args, err = builder.ToSql()
+	queryStr, args, err := builder.ToSql()
 	if err != nil {
-		st.log.Debug("Failed to build sql query to get total songs with filters",
+		st.log.Debug("Failed to build sql query to search songs by lyrics",
 			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
 		)
 		return nil, 0, err
 	}
 
-	var count int
-	err = st.db.QueryRow(queryStr, args...).Scan(&count)
+	rows, err := st.db.QueryContext(ctx, queryStr, args...)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, 0, &NotFoundErr{}
+		st.log.Debug("Failed to execute query in SearchSongsByLyrics",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	songs := make([]*entities.Song, 0)
+	songsByID := make(map[string]*entities.Song)
+	ids := make([]string, 0)
+	for rows.Next() {
+		s := entities.Song{}
+		if err := rows.Scan(append(songFields(&s), &s.Rank)...); err != nil {
+			st.log.Debug("Failed to scan row in SearchSongsByLyrics")
+			return nil, 0, err
 		}
+		songs = append(songs, &s)
+		songsByID[*s.ID] = &s
+		ids = append(ids, *s.ID)
+	}
+	if err = rows.Err(); err != nil {
+		st.log.Debug("Failed to scan rows in SearchSongsByLyrics")
+		return nil, 0, err
+	}
 
-		st.log.Debug("Failed to execute query for total songs in GetSongsWithFilters",
+	if len(ids) > 0 {
+		matchBuilder := sq.Select("verses.song_id", "verses.num").
+			Column(sq.Expr("ts_headline('simple', t.content, websearch_to_tsquery('simple', ?))", *opts.Lyrics)).
+			Column(sq.Expr("ts_rank(to_tsvector('simple', t.content), websearch_to_tsquery('simple', ?)) AS rank", *opts.Lyrics)).
+			From("verses").
+			Join("verse_texts t ON t.id = verses.text_id").
+			Where(sq.Eq{"verses.song_id": ids}).
+			Where(sq.Expr("to_tsvector('simple', t.content) @@ websearch_to_tsquery('simple', ?)", *opts.Lyrics)).
+			OrderBy("verses.song_id", "rank DESC", "verses.num").
+			PlaceholderFormat(sq.Dollar)
+
+		queryStr, args, err = matchBuilder.ToSql()
+		if err != nil {
+			st.log.Debug("Failed to build sql query to get matching verses",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return nil, 0, err
+		}
+
+		matchRows, err := st.db.QueryContext(ctx, queryStr, args...)
+		if err != nil {
+			st.log.Debug("Failed to execute query for matching verses in SearchSongsByLyrics",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return nil, 0, err
+		}
+		defer matchRows.Close()
+
+		for matchRows.Next() {
+			var songID string
+			m := entities.VerseMatch{}
+			if err := matchRows.Scan(&songID, &m.Number, &m.Snippet, &m.Rank); err != nil {
+				st.log.Debug("Failed to scan matching verse in SearchSongsByLyrics")
+				return nil, 0, err
+			}
+			if s, ok := songsByID[songID]; ok {
+				s.Matches = append(s.Matches, &m)
+			}
+		}
+		if err = matchRows.Err(); err != nil {
+			st.log.Debug("Failed to scan matching verses in SearchSongsByLyrics")
+			return nil, 0, err
+		}
+	}
+
+	countBuilder := sq.Select("count(DISTINCT songs.id)").
+		From("songs").
+		Join("verse_texts v ON v.song_id = songs.id").
+		Where(sq.Expr("to_tsvector('simple', v.content) @@ websearch_to_tsquery('simple', ?)", *opts.Lyrics))
+	countBuilder = st.AddSearchOptionsToBuilder(countBuilder, opts, false)
+	countBuilder = countBuilder.PlaceholderFormat(sq.Dollar)
+
+	queryStr, args, err = countBuilder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get total songs matching lyrics",
+			zap.String("message", err.Error()),
+		)
+		return nil, 0, err
+	}
+
+	var count int
+	err = st.db.QueryRowContext(ctx, queryStr, args...).Scan(&count)
+	if err != nil {
+		st.log.Debug("Failed to execute query for total songs in SearchSongsByLyrics",
 			zap.String("message", err.Error()),
 		)
 		return nil, 0, err
@@ -130,8 +450,19 @@ func (st *SongStorage) GetSongsWithFilters(opts *entities.SongSearchOptions) ([]
 	return songs, count, err
 }
 
-func (st *SongStorage) DeleteSong(id string) error {
-	builder := sq.Delete("songs").Where(sq.Eq{"id": id}).PlaceholderFormat(sq.Dollar)
+// DeleteSong hides the song until it is restored or purged, its verses and
+// places in albums and playlists are kept. When versions are given the song is
+// only deleted at one of them.
+func (st *SongStorage) DeleteSong(ctx context.Context, id string, versions []int) error {
+	builder := sq.Update("songs").
+		Set("deleted_at", sq.Expr("now()")).
+		Set("version", sq.Expr("version + 1")).
+		Where(sq.Eq{"id": id}).
+		Where("deleted_at IS NULL")
+	if versions != nil {
+		builder = builder.Where(sq.Eq{"version": versions})
+	}
+	builder = builder.PlaceholderFormat(sq.Dollar)
 
 	queryStr, args, err := builder.ToSql()
 	if err != nil {
@@ -142,22 +473,217 @@ func (st *SongStorage) DeleteSong(id string) error {
 		return err
 	}
 
-	_, err = st.db.Exec(queryStr, args...)
+	res, err := st.db.ExecContext(ctx, queryStr, args...)
 	if err != nil {
 		st.log.Debug("Failed to execute query in DeleteSong",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
+		return mapError(err, "song", id)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return &entities.NotFoundError{Resource: "song", ID: id}
+	}
+	return err
+}
+
+// RestoreSong brings back the deleted song with the current name of its group,
+// which could be renamed meanwhile.
+func (st *SongStorage) RestoreSong(ctx context.Context, id string) (*entities.Song, error) {
+	builder := sq.Update("songs").
+		Set("deleted_at", nil).
+		Set("group_name", sq.Expr("COALESCE((SELECT name FROM groups WHERE groups.id = songs.group_id), group_name)")).
+		Set("version", sq.Expr("version + 1")).
+		Where(sq.Eq{"id": id}).
+		Where("deleted_at IS NOT NULL").
+		Suffix("RETURNING " + strings.Join(songColumns, ", ")).
+		PlaceholderFormat(sq.Dollar)
+
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to restore song",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	s, err := scanSong(st.db.QueryRowContext(ctx, queryStr, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &entities.NotFoundError{Resource: "deleted song", ID: id}
+		}
+
+		st.log.Debug("Failed to execute query in RestoreSong",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "song", id)
+	}
+	return s, err
+}
+
+// GetDeletedSongs lists deleted songs, most recently deleted first.
+func (st *SongStorage) GetDeletedSongs(ctx context.Context, opts *entities.DeletedSongSearchOptions) ([]*entities.Song, int, error) {
+	builder := sq.Select(songColumns...).
+		From("songs").
+		Where("deleted_at IS NOT NULL").
+		OrderBy("deleted_at DESC", "id")
+	if opts.Page != nil && opts.PerPage != nil {
+		builder = builder.Offset((uint64)(*opts.PerPage * (*opts.Page - 1))).Limit(uint64(*opts.PerPage))
+	}
+	builder = builder.PlaceholderFormat(sq.Dollar)
+
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get deleted songs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, 0, err
+	}
+
+	rows, err := st.db.QueryContext(ctx, queryStr, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in GetDeletedSongs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	songs := make([]*entities.Song, 0)
+	for rows.Next() {
+		s, err := scanSong(rows)
+		if err != nil {
+			st.log.Debug("Failed to scan row in GetDeletedSongs")
+			return nil, 0, err
+		}
+		songs = append(songs, s)
+	}
+	if err = rows.Err(); err != nil {
+		st.log.Debug("Failed to scan rows in GetDeletedSongs")
+		return nil, 0, err
+	}
+
+	countBuilder := sq.Select("count(*)").
+		From("songs").
+		Where("deleted_at IS NOT NULL").
+		PlaceholderFormat(sq.Dollar)
+	queryStr, args, err = countBuilder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to count deleted songs",
+			zap.String("message", err.Error()),
+		)
+		return nil, 0, err
+	}
+
+	var count int
+	err = st.db.QueryRowContext(ctx, queryStr, args...).Scan(&count)
+	if err != nil {
+		st.log.Debug("Failed to execute query to count deleted songs",
+			zap.String("message", err.Error()),
+		)
+		return nil, 0, err
+	}
+	return songs, count, err
+}
+
+// GetPurgeableSongs returns ids of at most limit songs deleted before the given time.
+func (st *SongStorage) GetPurgeableSongs(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	builder := sq.Select("id").
+		From("songs").
+		Where(sq.Lt{"deleted_at": before}).
+		OrderBy("deleted_at").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar)
+
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get purgeable songs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	rows, err := st.db.QueryContext(ctx, queryStr, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in GetPurgeableSongs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			st.log.Debug("Failed to scan row in GetPurgeableSongs")
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		st.log.Debug("Failed to scan rows in GetPurgeableSongs")
+	}
+	return ids, err
+}
+
+// PurgeSong permanently removes the deleted song, its verses, lines and
+// enrichment jobs go with it.
+func (st *SongStorage) PurgeSong(ctx context.Context, id string) error {
+	builder := sq.Delete("songs").
+		Where(sq.Eq{"id": id}).
+		Where("deleted_at IS NOT NULL").
+		PlaceholderFormat(sq.Dollar)
+
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to purge song",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
 		return err
 	}
 
+	res, err := st.db.ExecContext(ctx, queryStr, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in PurgeSong",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return mapError(err, "song", id)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return &entities.NotFoundError{Resource: "deleted song", ID: id}
+	}
 	return err
 }
 
-func (st *SongStorage) UpdateSong(id string, song entities.Song) (*entities.Song, error) {
-	builder := sq.Update("songs").Where(sq.Eq{"id": id})
+// UpdateSong sets the given fields of the song. When versions are given the song
+// is only updated at one of them.
+func (st *SongStorage) UpdateSong(ctx context.Context, id string, song entities.Song, versions []int) (*entities.Song, error) {
+	builder := sq.Update("songs").Where(sq.Eq{"id": id}).Where("deleted_at IS NULL")
+	if versions != nil {
+		builder = builder.Where(sq.Eq{"version": versions})
+	}
 	builder = st.AddUpdateOptionsToBuilder(builder, &song)
-	builder = builder.Suffix("RETURNING songs.*").PlaceholderFormat(sq.Dollar)
+	builder = builder.Set("version", sq.Expr("version + 1"))
+	builder = builder.Suffix("RETURNING " + strings.Join(songColumns, ", ")).PlaceholderFormat(sq.Dollar)
 
 	queryStr, args, err := builder.ToSql()
 	if err != nil {
@@ -168,24 +694,213 @@ func (st *SongStorage) UpdateSong(id string, song entities.Song) (*entities.Song
 		return nil, err
 	}
 
-	err = st.db.QueryRow(queryStr, args...).Scan(&song.ID, &song.Group, &song.Song, &song.ReleaseDate, &song.Link)
+	s, err := scanSong(st.db.QueryRowContext(ctx, queryStr, args...))
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &entities.NotFoundError{Resource: "song", ID: id}
+		}
+
 		st.log.Debug("Failed to execute query in UpdateSong",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
+		return nil, mapError(err, "song", id)
+	}
+	return s, err
+}
+
+// ExportSongs walks songs matching the options in sort order with a server-side
+// cursor, fetching batch songs with their verses at a time, and calls fn for each.
+// It must run inside a transaction.
+func (st *SongStorage) ExportSongs(ctx context.Context, opts *entities.SongSearchOptions, batch int, fn func(s *entities.ExportedSong) error) error {
+	builder := sq.Select(append(qualify("songs", songColumns), "lyr.nums", "lyr.contents", "lyr.labels")...).
+		From("songs").
+		JoinClause("LEFT JOIN LATERAL (SELECT array_agg(v.num ORDER BY v.num) AS nums, array_agg(t.content ORDER BY v.num) AS contents, " +
+			"array_agg(coalesce(v.label, '') ORDER BY v.num) AS labels " +
+			"FROM verses v JOIN verse_texts t ON t.id = v.text_id WHERE v.song_id = songs.id) lyr ON true")
+	builder = st.AddSearchOptionsToBuilder(builder, opts, false)
+	builder = orderBy(builder, songSortKeys(opts.Sort), false)
+	builder = builder.Prefix("DECLARE song_export NO SCROLL CURSOR FOR").PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to export songs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	if _, err = st.db.ExecContext(ctx, query, args...); err != nil {
+		st.log.Debug("Failed to declare cursor in ExportSongs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+	defer st.db.ExecContext(ctx, "CLOSE song_export")
+
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM song_export", batch)
+	for {
+		fetched, err := st.fetchExport(ctx, fetch, fn)
+		if err != nil {
+			return err
+		}
+		if fetched < batch {
+			return nil
+		}
+	}
+}
+
+func (st *SongStorage) fetchExport(ctx context.Context, fetch string, fn func(s *entities.ExportedSong) error) (int, error) {
+	rows, err := st.db.QueryContext(ctx, fetch)
+	if err != nil {
+		st.log.Debug("Failed to fetch from cursor in ExportSongs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return 0, err
+	}
+	defer rows.Close()
+
+	fetched := 0
+	for rows.Next() {
+		s := entities.ExportedSong{}
+		var nums pq.Int64Array
+		var contents, labels pq.StringArray
+		if err := rows.Scan(append(songFields(&s.Song), &nums, &contents, &labels)...); err != nil {
+			st.log.Debug("Failed to scan row in ExportSongs")
+			return fetched, err
+		}
+
+		s.Verses = make([]*entities.Verse, 0, len(nums))
+		for i := range nums {
+			v := &entities.Verse{SongID: *s.ID, Number: int(nums[i]), Content: contents[i]}
+			if labels[i] != "" {
+				v.Label = &labels[i]
+			}
+			s.Verses = append(s.Verses, v)
+		}
+		fetched++
+		if err := fn(&s); err != nil {
+			return fetched, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		st.log.Debug("Failed to scan rows in ExportSongs")
+	}
+	return fetched, err
+}
+
+// GetGroupSongIDs returns ids of the songs of the group that are not deleted.
+func (st *SongStorage) GetGroupSongIDs(ctx context.Context, groupId string) ([]string, error) {
+	builder := sq.Select("id").
+		From("songs").
+		Where(sq.Eq{"group_id": groupId}).
+		Where("deleted_at IS NULL").
+		OrderBy("id").
+		PlaceholderFormat(sq.Dollar)
+
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get group song ids",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
 		return nil, err
 	}
-	return &song, err
+
+	rows, err := st.db.QueryContext(ctx, queryStr, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in GetGroupSongIDs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			st.log.Debug("Failed to scan row in GetGroupSongIDs")
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		st.log.Debug("Failed to scan rows in GetGroupSongIDs")
+	}
+	return ids, err
+}
+
+// RenameGroupSongs updates the group name copied to every song of the group
+// that is not deleted, deleted songs take the name when they are restored.
+func (st *SongStorage) RenameGroupSongs(ctx context.Context, groupId string, name string) error {
+	builder := sq.Update("songs").
+		Set("group_name", name).
+		Set("version", sq.Expr("version + 1")).
+		Where(sq.Eq{"group_id": groupId}).
+		Where("deleted_at IS NULL").
+		PlaceholderFormat(sq.Dollar)
+
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to rename group songs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	_, err = st.db.ExecContext(ctx, queryStr, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in RenameGroupSongs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+	return err
 }
 
 func (st *SongStorage) AddSearchOptionsToBuilder(builder sq.SelectBuilder, opts *entities.SongSearchOptions, enablePagination bool) sq.SelectBuilder {
+	builder = builder.Where("songs.deleted_at IS NULL")
+
 	if opts.Group != nil {
-		builder = builder.Where(sq.Eq{"group_name": *opts.Group})
+		builder = builder.Where(matchCondition("group_name", *opts.Group, opts.Match))
+	}
+
+	if opts.GroupID != nil {
+		builder = builder.Where(sq.Eq{"group_id": *opts.GroupID})
+	}
+
+	if opts.Album != nil {
+		builder = builder.Where("EXISTS (SELECT 1 FROM album_tracks t WHERE t.song_id = songs.id AND t.album_id = ?)", *opts.Album)
+	}
+
+	if len(opts.Groups) > 0 {
+		builder = builder.Where(sq.Eq{"group_name": opts.Groups})
 	}
 
 	if opts.Song != nil {
-		builder = builder.Where(sq.Eq{"song": *opts.Song})
+		builder = builder.Where(matchCondition("song", *opts.Song, opts.Match))
+	}
+
+	if opts.HasLink != nil {
+		if *opts.HasLink {
+			builder = builder.Where("link IS NOT NULL AND link <> ''")
+		} else {
+			builder = builder.Where("(link IS NULL OR link = '')")
+		}
+	}
+
+	if opts.HasLyrics != nil {
+		if *opts.HasLyrics {
+			builder = builder.Where("EXISTS (SELECT 1 FROM verses WHERE verses.song_id = songs.id)")
+		} else {
+			builder = builder.Where("NOT EXISTS (SELECT 1 FROM verses WHERE verses.song_id = songs.id)")
+		}
 	}
 
 	if opts.ReleaseDateAfter != nil {
@@ -202,11 +917,80 @@ func (st *SongStorage) AddSearchOptionsToBuilder(builder sq.SelectBuilder, opts
 	return builder
 }
 
+// matchCondition compares the column with the value exactly or, for prefix
+// and contains modes, case-insensitively with LIKE.
+func matchCondition(column, value string, mode *string) sq.Sqlizer {
+	if mode == nil || *mode == entities.MatchExact {
+		return sq.Eq{column: value}
+	}
+
+	pattern := likeEscaper.Replace(strings.ToLower(value)) + "%"
+	if *mode == entities.MatchContains {
+		pattern = "%" + pattern
+	}
+	return sq.Expr("lower("+column+") LIKE ?", pattern)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// songSortExprs maps sort fields accepted in SongSearchOptions.Sort to
+// non-nullable expressions, so they can take part in keyset comparisons.
+var songSortExprs = map[string]string{
+	"id":          "songs.id",
+	"song":        "coalesce(songs.song, '')",
+	"group":       "coalesce(songs.group_name, '')",
+	"releaseDate": "coalesce(songs.release_date, '-infinity'::date)",
+}
+
+// songSortKeys turns sort fields like "-releaseDate" into sort keys. The id
+// is always appended as the last key to make the order stable.
+func songSortKeys(sort []string) []sortKey {
+	keys := make([]sortKey, 0, len(sort)+1)
+	seen := make(map[string]bool)
+	for _, f := range append(sort[:len(sort):len(sort)], "id") {
+		desc := strings.HasPrefix(f, "-")
+		f = strings.TrimPrefix(f, "-")
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+		keys = append(keys, sortKey{Field: f, Expr: songSortExprs[f], Desc: desc})
+	}
+	return keys
+}
+
+// songSortValue returns the value of the sort field in the form it is
+// compared in songSortExprs.
+func songSortValue(s *entities.Song, field string) string {
+	switch field {
+	case "song":
+		if s.Song != nil {
+			return *s.Song
+		}
+	case "group":
+		if s.Group != nil {
+			return *s.Group
+		}
+	case "releaseDate":
+		if s.ReleaseDate != nil {
+			return s.ReleaseDate.Format("2006-01-02")
+		}
+		return "-infinity"
+	default:
+		return *s.ID
+	}
+	return ""
+}
+
 func (st *SongStorage) AddUpdateOptionsToBuilder(builder sq.UpdateBuilder, song *entities.Song) sq.UpdateBuilder {
 	if song.Group != nil {
 		builder = builder.Set("group_name", *song.Group)
 	}
 
+	if song.GroupID != nil {
+		builder = builder.Set("group_id", *song.GroupID)
+	}
+
 	if song.Song != nil {
 		builder = builder.Set("song", *song.Song)
 	}
@@ -217,5 +1001,8 @@ func (st *SongStorage) AddUpdateOptionsToBuilder(builder sq.UpdateBuilder, song
 	if song.ReleaseDate != nil {
 		builder = builder.Set("release_date", *song.ReleaseDate)
 	}
+	if song.EnrichmentStatus != nil {
+		builder = builder.Set("enrichment_status", *song.EnrichmentStatus)
+	}
 	return builder
 }
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testEM/internal/usecase"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Querier is the subset of methods shared by *sql.DB and *sql.Tx,
+// so storages can run either on the pool or inside a transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// NewRepositories returns all storages running on db, which is either
+// the connection pool or a transaction.
+func NewRepositories(db Querier, log *zap.Logger) usecase.Repositories {
+	return usecase.Repositories{
+		Songs:      NewSongStorage(db, log),
+		Verses:     NewVerseStorage(db, log),
+		Lines:      NewLineStorage(db, log),
+		Enrichment: NewEnrichmentStorage(db, log),
+		Groups:     NewGroupStorage(db, log),
+		Albums:     NewAlbumStorage(db, log),
+		Playlists:  NewPlaylistStorage(db, log),
+		Revisions:  NewRevisionStorage(db, log),
+		Audit:      NewAuditStorage(db, log),
+	}
+}
+
+type UnitOfWork struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewUnitOfWork(db *sql.DB, log *zap.Logger) *UnitOfWork {
+	return &UnitOfWork{
+		db:  db,
+		log: log,
+	}
+}
+
+// Do runs fn with repositories bound to a single transaction. The transaction
+// is committed if fn succeeds and rolled back otherwise, it is also rolled
+// back when ctx is done.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(r usecase.Repositories) error) (err error) {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		u.log.Debug("Failed to begin transaction",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	err = fn(NewRepositories(tx, u.log))
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			u.log.Debug("Failed to rollback transaction",
+				zap.String("message", rbErr.Error()),
+				zap.Time("time", time.Now()),
+			)
+		}
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		u.log.Debug("Failed to commit transaction",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+	return err
+}
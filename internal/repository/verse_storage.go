@@ -1,8 +1,10 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"strconv"
 	"testEM/internal/entities"
 	"time"
 
@@ -11,26 +13,85 @@ import (
 )
 
 type VerseStorage struct {
-	db  *sql.DB
+	db  Querier
 	log *zap.Logger
 }
 
-func NewVerseStorage(db *sql.DB, log *zap.Logger) *VerseStorage {
+func NewVerseStorage(db Querier, log *zap.Logger) *VerseStorage {
 	return &VerseStorage{
 		db:  db,
 		log: log,
 	}
 }
 
-func (st *VerseStorage) AddVersesForSong(songId string, verses []*entities.Verse) error {
-	builder := sq.Insert("verses").Columns("song_id", "num", "content")
+// verseColumns are selected from verses joined with their texts as t.
+var verseColumns = []string{"verses.song_id", "verses.num", "t.content", "verses.label", "t.part"}
 
+func scanVerse(row scanner) (*entities.Verse, error) {
+	v := entities.Verse{}
+	if err := row.Scan(&v.SongID, &v.Number, &v.Content, &v.Label, &v.Part); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// textId looks up the stored text of the song by content.
+func textId(songId string, content string) sq.Sqlizer {
+	return sq.Expr("(SELECT id FROM verse_texts WHERE song_id = ? AND md5(content) = md5(?::text))", songId, content)
+}
+
+func (st *VerseStorage) AddVersesForSong(ctx context.Context, songId string, verses []*entities.Verse) error {
 	for _, verse := range verses {
-		builder = builder.Values(songId, verse.Number, verse.Content)
+		verse.SongID = songId
+	}
+	return mapError(st.addVerses(ctx, verses), "song", songId)
+}
+
+// AddVerses inserts verses of several songs, texts repeated within a song are stored once.
+func (st *VerseStorage) AddVerses(ctx context.Context, verses []*entities.Verse) error {
+	return mapError(st.addVerses(ctx, verses), "song", "")
+}
+
+func (st *VerseStorage) addVerses(ctx context.Context, verses []*entities.Verse) error {
+	if len(verses) == 0 {
+		return nil
+	}
+
+	texts := sq.Insert("verse_texts").Columns("song_id", "content", "part")
+	seen := make(map[[2]string]bool)
+	for _, verse := range verses {
+		key := [2]string{verse.SongID, verse.Content}
+		if !seen[key] {
+			seen[key] = true
+			texts = texts.Values(verse.SongID, verse.Content, verse.Part)
+		}
+	}
+	texts = texts.Suffix("ON CONFLICT (song_id, md5(content)) DO NOTHING").PlaceholderFormat(sq.Dollar)
+	query, args, err := texts.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to add verse texts",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	_, err = st.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		st.log.Debug("Failed to add verse texts",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	builder := sq.Insert("verses").Columns("song_id", "num", "label", "text_id")
+	for _, verse := range verses {
+		builder = builder.Values(verse.SongID, verse.Number, verse.Label, textId(verse.SongID, verse.Content))
 	}
 
 	builder = builder.PlaceholderFormat(sq.Dollar)
-	query, args, err := builder.ToSql()
+	query, args, err = builder.ToSql()
 	if err != nil {
 		st.log.Debug("Failed to build sql query to add verses",
 			zap.String("message", err.Error()),
@@ -39,20 +100,32 @@ func (st *VerseStorage) AddVersesForSong(songId string, verses []*entities.Verse
 		return err
 	}
 
-	_, err = st.db.Exec(query, args...)
+	_, err = st.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		st.log.Debug("Failed to add text song to verses",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
 	}
-
 	return err
 }
 
-func (st *VerseStorage) GetVersesForSong(opts entities.VerseSearchOptions) ([]*entities.Verse, int, error) {
-	builder := sq.Select("*").From("verses")
+func (st *VerseStorage) GetVersesForSong(ctx context.Context, opts entities.VerseSearchOptions) ([]*entities.Verse, entities.Page, error) {
+	keyset := isKeyset(opts.Page, opts.Cursor, opts.PerPage)
+	// num is unique within a song, so it is a stable key for the verses listing
+	keys := []sortKey{{Field: "num", Expr: "num"}}
+	cur, err := decodeCursor(opts.Cursor, len(keys))
+	if err != nil {
+		return nil, entities.Page{}, err
+	}
+
+	builder := sq.Select(verseColumns...).From("verses").Join("verse_texts t ON t.id = verses.text_id")
 	builder = st.AddSearchOptionsToBuilder(builder, &opts, true)
+	if keyset {
+		builder = applyKeyset(builder, keys, cur, pageSize(opts.PerPage))
+	} else {
+		builder = orderBy(builder, keys, false)
+	}
 	builder = builder.PlaceholderFormat(sq.Dollar)
 	queryStr, args, err := builder.ToSql()
 	if err != nil {
@@ -60,34 +133,43 @@ func (st *VerseStorage) GetVersesForSong(opts entities.VerseSearchOptions) ([]*e
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		return nil, 0, err
+		return nil, entities.Page{}, err
 	}
 
 	verses := make([]*entities.Verse, 0)
-	rows, err := st.db.Query(queryStr, args...)
+	rows, err := st.db.QueryContext(ctx, queryStr, args...)
 	if err != nil {
 		st.log.Debug("Failed to execute query to get verses",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		return nil, 0, err
+		return nil, entities.Page{}, mapError(err, "song", "")
 	}
+	defer rows.Close()
 
 	for rows.Next() {
-		v := entities.Verse{}
-		var id int
-		if err := rows.Scan(&id, &v.SongID, &v.Number, &v.Content); err != nil {
+		v, err := scanVerse(rows)
+		if err != nil {
 			st.log.Debug("Failed to scan row in GetVersesForSong")
-			return nil, 0, err
+			return nil, entities.Page{}, err
 		}
-		verses = append(verses, &v)
+		verses = append(verses, v)
 	}
 
 	if err = rows.Err(); err != nil {
 		st.log.Debug("Failed to scan rows in GetVersesForSong")
-		return nil, 0, err
+		return nil, entities.Page{}, err
+	}
+
+	page := entities.Page{}
+	if keyset {
+		verses, page = buildPage(verses, cur, pageSize(opts.PerPage), func(v *entities.Verse) []string {
+			return []string{strconv.Itoa(v.Number)}
+		})
+		if opts.WithTotal == nil || !*opts.WithTotal {
+			return verses, page, err
+		}
 	}
-	defer rows.Close()
 
 	builder = sq.Select("count(*)").From("verses")
 	builder = st.AddSearchOptionsToBuilder(builder, &opts, false)
@@ -97,26 +179,23 @@ func (st *VerseStorage) GetVersesForSong(opts entities.VerseSearchOptions) ([]*e
 		st.log.Debug("Failed to build sql query to get total verses in GetVersesForSong",
 			zap.String("message", err.Error()),
 		)
-		return nil, 0, err
+		return nil, entities.Page{}, err
 	}
 
 	var count int
-	err = st.db.QueryRow(queryStr, args...).Scan(&count)
+	err = st.db.QueryRowContext(ctx, queryStr, args...).Scan(&count)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, 0, &NotFoundErr{}
-		}
-
 		st.log.Debug("Failed to execute query fot total verses in GetVersesForSong",
 			zap.String("message", err.Error()),
 		)
-		return nil, 0, err
+		return nil, entities.Page{}, err
 	}
+	page.Total = &count
 
-	return verses, count, err
+	return verses, page, err
 }
 
-func (st *VerseStorage) DeleteSong(id string) error {
+func (st *VerseStorage) DeleteSong(ctx context.Context, id string) error {
 	builder := sq.Delete("verses").Where(sq.Eq{"song_id": id}).PlaceholderFormat(sq.Dollar)
 	queryStr, args, err := builder.ToSql()
 	if err != nil {
@@ -127,19 +206,21 @@ func (st *VerseStorage) DeleteSong(id string) error {
 		return err
 	}
 
-	_, err = st.db.Exec(queryStr, args...)
+	_, err = st.db.ExecContext(ctx, queryStr, args...)
 	if err != nil {
 		st.log.Debug("Failed to get text song from verses",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
+		return mapError(err, "song", id)
 	}
-	return err
+	return st.DeleteUnusedTexts(ctx, id)
 }
 
 func (st *VerseStorage) AddSearchOptionsToBuilder(builder sq.SelectBuilder, opts *entities.VerseSearchOptions, enablePagination bool) sq.SelectBuilder {
+	builder = builder.Where(songNotDeleted("verses.song_id"))
 	if opts.SongID != nil {
-		builder = builder.Where(sq.Eq{"song_id": *opts.SongID})
+		builder = builder.Where(sq.Eq{"verses.song_id": *opts.SongID})
 	}
 
 	if enablePagination && opts.Page != nil && opts.PerPage != nil {
@@ -147,3 +228,226 @@ func (st *VerseStorage) AddSearchOptionsToBuilder(builder sq.SelectBuilder, opts
 	}
 	return builder
 }
+
+func (st *VerseStorage) GetVerse(ctx context.Context, songId string, num int) (*entities.Verse, error) {
+	builder := sq.Select(verseColumns...).From("verses").
+		Join("verse_texts t ON t.id = verses.text_id").
+		Where(sq.Eq{"verses.song_id": songId, "verses.num": num}).
+		Where(songNotDeleted("verses.song_id")).
+		PlaceholderFormat(sq.Dollar)
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to get verse",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	v, err := scanVerse(st.db.QueryRowContext(ctx, queryStr, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &entities.NotFoundError{Resource: "verse", ID: strconv.Itoa(num)}
+		}
+
+		st.log.Debug("Failed to execute query in GetVerse",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "song", songId)
+	}
+	return v, err
+}
+
+func (st *VerseStorage) CountVerses(ctx context.Context, songId string) (int, error) {
+	builder := sq.Select("count(*)").From("verses").
+		Where(sq.Eq{"song_id": songId}).
+		PlaceholderFormat(sq.Dollar)
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to count verses",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return 0, err
+	}
+
+	var count int
+	err = st.db.QueryRowContext(ctx, queryStr, args...).Scan(&count)
+	if err != nil {
+		st.log.Debug("Failed to execute query in CountVerses",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return 0, err
+	}
+	return count, err
+}
+
+// UpdateVerse points the verse to the text with the new content, storing
+// it unless the song already has it.
+func (st *VerseStorage) UpdateVerse(ctx context.Context, verse *entities.Verse) (*entities.Verse, error) {
+	builder := sq.Update("verses").
+		Prefix("WITH text AS (INSERT INTO verse_texts (song_id, content, part) VALUES (?, ?, ?) "+
+			"ON CONFLICT (song_id, md5(content)) DO UPDATE SET content = EXCLUDED.content RETURNING id)",
+			verse.SongID, verse.Content, verse.Part).
+		Set("text_id", sq.Expr("(SELECT id FROM text)")).
+		Set("label", verse.Label).
+		Where(sq.Eq{"song_id": verse.SongID, "num": verse.Number}).
+		Suffix("RETURNING song_id, num, label").
+		PlaceholderFormat(sq.Dollar)
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to update verse",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	v := entities.Verse{Content: verse.Content, Part: verse.Part}
+	err = st.db.QueryRowContext(ctx, queryStr, args...).Scan(&v.SongID, &v.Number, &v.Label)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &entities.NotFoundError{Resource: "verse", ID: strconv.Itoa(verse.Number)}
+		}
+
+		st.log.Debug("Failed to execute query in UpdateVerse",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "song", verse.SongID)
+	}
+	return &v, err
+}
+
+// InsertVerse shifts verses starting from num one position down and inserts
+// the new verse in the freed slot within a single statement, the text is
+// stored unless the song already has it.
+func (st *VerseStorage) InsertVerse(ctx context.Context, verse *entities.Verse) (*entities.Verse, error) {
+	builder := sq.Insert("verses").
+		Prefix("WITH shifted AS (UPDATE verses SET num = num + 1 WHERE song_id = ? AND num >= ?), "+
+			"text AS (INSERT INTO verse_texts (song_id, content, part) VALUES (?, ?, ?) "+
+			"ON CONFLICT (song_id, md5(content)) DO UPDATE SET content = EXCLUDED.content RETURNING id)",
+			verse.SongID, verse.Number, verse.SongID, verse.Content, verse.Part).
+		Columns("song_id", "num", "label", "text_id").
+		Values(verse.SongID, verse.Number, verse.Label, sq.Expr("(SELECT id FROM text)")).
+		Suffix("RETURNING song_id, num, label").
+		PlaceholderFormat(sq.Dollar)
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to insert verse",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	v := entities.Verse{Content: verse.Content, Part: verse.Part}
+	err = st.db.QueryRowContext(ctx, queryStr, args...).Scan(&v.SongID, &v.Number, &v.Label)
+	if err != nil {
+		st.log.Debug("Failed to execute query in InsertVerse",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, mapError(err, "song", verse.SongID)
+	}
+	return &v, err
+}
+
+// DeleteUnusedTexts removes texts of the song no verse refers to anymore.
+func (st *VerseStorage) DeleteUnusedTexts(ctx context.Context, songId string) error {
+	builder := sq.Delete("verse_texts").
+		Where(sq.Eq{"song_id": songId}).
+		Where("NOT EXISTS (SELECT 1 FROM verses WHERE verses.text_id = verse_texts.id)").
+		PlaceholderFormat(sq.Dollar)
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to delete unused verse texts",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	_, err = st.db.ExecContext(ctx, queryStr, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in DeleteUnusedTexts",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+	return mapError(err, "song", songId)
+}
+
+// DeleteVerse removes the verse and closes the gap in numbering
+// within a single statement.
+func (st *VerseStorage) DeleteVerse(ctx context.Context, songId string, num int) error {
+	builder := sq.Select("count(*)").From("deleted").
+		Prefix("WITH deleted AS (DELETE FROM verses WHERE song_id = ? AND num = ? RETURNING num), "+
+			"shifted AS (UPDATE verses SET num = num - 1 WHERE song_id = ? AND num > ? AND EXISTS (SELECT 1 FROM deleted))",
+			songId, num, songId, num).
+		PlaceholderFormat(sq.Dollar)
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to delete verse",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	var deleted int
+	err = st.db.QueryRowContext(ctx, queryStr, args...).Scan(&deleted)
+	if err != nil {
+		st.log.Debug("Failed to execute query in DeleteVerse",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return mapError(err, "song", songId)
+	}
+	if deleted == 0 {
+		return &entities.NotFoundError{Resource: "verse", ID: strconv.Itoa(num)}
+	}
+	return err
+}
+
+// MoveVerse moves the verse from one position to another, shifting the verses
+// in between by one within a single statement.
+func (st *VerseStorage) MoveVerse(ctx context.Context, songId string, from, to int) error {
+	lo, hi := from, to
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+
+	builder := sq.Update("verses").
+		Set("num", sq.Expr("CASE WHEN num = ? THEN ? WHEN ? < ? THEN num - 1 ELSE num + 1 END", from, to, from, to)).
+		Where(sq.Eq{"song_id": songId}).
+		Where(sq.Expr("num BETWEEN ? AND ?", lo, hi)).
+		PlaceholderFormat(sq.Dollar)
+	queryStr, args, err := builder.ToSql()
+	if err != nil {
+		st.log.Debug("Failed to build sql query to move verse",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	res, err := st.db.ExecContext(ctx, queryStr, args...)
+	if err != nil {
+		st.log.Debug("Failed to execute query in MoveVerse",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return mapError(err, "song", songId)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return &entities.NotFoundError{Resource: "verse", ID: strconv.Itoa(from)}
+	}
+	return err
+}
@@ -0,0 +1,323 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testEM/internal/entities"
+	"testEM/internal/validation"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func (uc *Usecase) GetAlbums(ctx context.Context, options entities.AlbumSearchOptions) (entities.AlbumsWrapper, error) {
+	if err := validation.Validate(&options); err != nil {
+		return entities.AlbumsWrapper{}, err
+	}
+
+	albums, page, err := uc.repos.Albums.GetAlbums(ctx, &options)
+	if err != nil {
+		uc.log.Error("Failed to get albums",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return entities.AlbumsWrapper{}, err
+	}
+
+	uc.log.Info("Recieved list of albums",
+		zap.Time("time", time.Now()),
+	)
+	resp := entities.AlbumsWrapper{
+		Albums: albums,
+		Page:   page,
+	}
+	return resp, err
+}
+
+// GetAlbum returns the album with its tracks.
+func (uc *Usecase) GetAlbum(ctx context.Context, id string) (*entities.Album, error) {
+	var a *entities.Album
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		var err error
+		a, err = uc.getAlbum(ctx, r, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Recieved album",
+		zap.Time("time", time.Now()),
+	)
+	return a, err
+}
+
+func (uc *Usecase) getAlbum(ctx context.Context, r Repositories, id string) (*entities.Album, error) {
+	a, err := r.Albums.GetAlbum(ctx, id)
+	if err != nil {
+		uc.log.Error("Failed to get album",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	a.Tracks, err = r.Albums.GetTracks(ctx, id)
+	if err != nil {
+		uc.log.Error("Failed to get album tracks",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+	return a, err
+}
+
+func (uc *Usecase) AddAlbum(ctx context.Context, dto entities.AddAlbumDTO, caller entities.Caller) (*entities.Album, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
+
+	album := entities.Album{
+		GroupID: dto.GroupID,
+		Title:   dto.Title,
+	}
+	if dto.ReleaseDate != nil {
+		date, err := time.Parse(DateLayout, *dto.ReleaseDate)
+		if err != nil {
+			return nil, entities.NewValidationError("releaseDate", "must be in "+DateLayout+" format")
+		}
+		album.ReleaseDate = &date
+	}
+
+	var a *entities.Album
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		var err error
+		a, err = r.Albums.AddAlbum(ctx, album)
+		if err != nil {
+			uc.log.Error("Failed to add album",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditAlbumAdd, entities.AuditAlbum, *a.ID, nil, a)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Added album",
+		zap.Time("time", time.Now()),
+	)
+	return a, err
+}
+
+func (uc *Usecase) PatchAlbum(ctx context.Context, id string, dto entities.PatchAlbumDTO, caller entities.Caller) (*entities.Album, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
+
+	album := entities.Album{
+		GroupID: dto.GroupID,
+		Title:   dto.Title,
+	}
+	if dto.ReleaseDate != nil {
+		date, err := time.Parse(DateLayout, *dto.ReleaseDate)
+		if err != nil {
+			return nil, entities.NewValidationError("releaseDate", "must be in "+DateLayout+" format")
+		}
+		album.ReleaseDate = &date
+	}
+
+	var a *entities.Album
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := r.Albums.GetAlbum(ctx, id)
+		if err != nil {
+			return err
+		}
+		a, err = r.Albums.UpdateAlbum(ctx, id, album)
+		if err != nil {
+			uc.log.Error("Failed to update album",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditAlbumPatch, entities.AuditAlbum, id, before, a)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Updated album",
+		zap.Time("time", time.Now()),
+	)
+	return a, err
+}
+
+// DeleteAlbum removes the album and its track list, songs are kept.
+func (uc *Usecase) DeleteAlbum(ctx context.Context, id string, caller entities.Caller) error {
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := uc.getAlbum(ctx, r, id)
+		if err != nil {
+			return err
+		}
+		err = r.Albums.DeleteAlbum(ctx, id)
+		if err != nil {
+			uc.log.Error("Failed to delete album",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditAlbumDelete, entities.AuditAlbum, id, before, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.log.Info("Deleted album",
+		zap.Time("time", time.Now()),
+	)
+	return err
+}
+
+// AddTrack puts the song on the album at the given track number,
+// appending it when the number is omitted.
+func (uc *Usecase) AddTrack(ctx context.Context, albumID string, dto entities.AddTrackDTO, caller entities.Caller) (*entities.Album, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
+
+	var a *entities.Album
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := uc.getAlbum(ctx, r, albumID)
+		if err != nil {
+			return err
+		}
+		if _, err := r.Songs.GetSong(ctx, *dto.SongID); err != nil {
+			return err
+		}
+
+		count, err := r.Albums.CountTracks(ctx, albumID)
+		if err != nil {
+			uc.log.Error("Failed to count tracks",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		num := count + 1
+		if dto.Number != nil {
+			num = *dto.Number
+		}
+		if num > count+1 {
+			return entities.NewValidationError("num", fmt.Sprintf("must be between 1 and %d", count+1))
+		}
+
+		err = r.Albums.AddTrack(ctx, albumID, *dto.SongID, num)
+		if err != nil {
+			uc.log.Error("Failed to add track",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		a, err = uc.getAlbum(ctx, r, albumID)
+		if err != nil {
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditAlbumTrackAdd, entities.AuditAlbum, albumID, before, a)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Added track to album",
+		zap.Time("time", time.Now()),
+	)
+	return a, err
+}
+
+func (uc *Usecase) RemoveTrack(ctx context.Context, albumID string, songID string, caller entities.Caller) error {
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := uc.getAlbum(ctx, r, albumID)
+		if err != nil {
+			return err
+		}
+		err = r.Albums.RemoveTrack(ctx, albumID, songID)
+		if err != nil {
+			uc.log.Error("Failed to remove track",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		after, err := uc.getAlbum(ctx, r, albumID)
+		if err != nil {
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditAlbumTrackRemove, entities.AuditAlbum, albumID, before, after)
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.log.Info("Removed track from album",
+		zap.Time("time", time.Now()),
+	)
+	return err
+}
+
+// MoveTrack changes the track number of the song, tracks in between are renumbered.
+func (uc *Usecase) MoveTrack(ctx context.Context, albumID string, songID string, dto entities.MoveTrackDTO, caller entities.Caller) (*entities.Album, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
+
+	var a *entities.Album
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := uc.getAlbum(ctx, r, albumID)
+		if err != nil {
+			return err
+		}
+		count, err := r.Albums.CountTracks(ctx, albumID)
+		if err != nil {
+			uc.log.Error("Failed to count tracks",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		if *dto.To > count {
+			return entities.NewValidationError("to", fmt.Sprintf("must be between 1 and %d", count))
+		}
+
+		err = r.Albums.MoveTrack(ctx, albumID, songID, *dto.To)
+		if err != nil {
+			uc.log.Error("Failed to move track",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		a, err = uc.getAlbum(ctx, r, albumID)
+		if err != nil {
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditAlbumTrackMove, entities.AuditAlbum, albumID, before, a)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Moved track",
+		zap.Time("time", time.Now()),
+	)
+	return a, err
+}
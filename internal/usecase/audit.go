@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testEM/internal/entities"
+	"testEM/internal/validation"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// audit appends the change to the audit log. It must run in the transaction
+// of the change, so only committed changes are recorded.
+func (uc *Usecase) audit(ctx context.Context, r Repositories, caller entities.Caller, action, resource, id string, before, after any) error {
+	e := entities.AuditEntry{
+		Actor:      caller.Actor,
+		Action:     action,
+		Resource:   resource,
+		ResourceID: id,
+	}
+	if caller.RequestID != "" {
+		e.RequestID = &caller.RequestID
+	}
+	if caller.ClientIP != "" {
+		e.ClientIP = &caller.ClientIP
+	}
+
+	err := r.Audit.AddEntry(ctx, e, before, after)
+	if err != nil {
+		uc.log.Error("Failed to add audit entry",
+			zap.String("message", err.Error()),
+			zap.String("action", action),
+			zap.String("id", id),
+			zap.Time("time", time.Now()),
+		)
+	}
+	return err
+}
+
+// songBefore takes the snapshot of the song ahead of a change, it is nil for
+// a song that doesn't exist or is deleted.
+func songBefore(ctx context.Context, r Repositories, songID string) (*entities.SongSnapshot, error) {
+	snapshot, err := songSnapshot(ctx, r, songID)
+	if errors.Is(err, entities.ErrNotFound) {
+		return nil, nil
+	}
+	return snapshot, err
+}
+
+// recordSongChange records the change of the song in its history and in the
+// audit log, before is the snapshot taken by songBefore.
+func (uc *Usecase) recordSongChange(ctx context.Context, r Repositories, caller entities.Caller, songID, revision, action string, before *entities.SongSnapshot) error {
+	after, err := uc.recordRevision(ctx, r, songID, revision, caller.Actor)
+	if err != nil {
+		return err
+	}
+	return uc.audit(ctx, r, caller, action, entities.AuditSong, songID, before, after)
+}
+
+// GetAudit lists audit entries newest first.
+func (uc *Usecase) GetAudit(ctx context.Context, options entities.AuditSearchOptions) (entities.AuditWrapper, error) {
+	if err := validation.Validate(&options); err != nil {
+		return entities.AuditWrapper{}, err
+	}
+
+	entries, total, err := uc.repos.Audit.GetEntries(ctx, &options)
+	if err != nil {
+		uc.log.Error("Failed to get audit entries",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return entities.AuditWrapper{}, err
+	}
+
+	uc.log.Info("Recieved audit entries",
+		zap.Time("time", time.Now()),
+	)
+	return entities.AuditWrapper{
+		Entries: entries,
+		Page:    entities.Page{Total: &total},
+	}, err
+}
@@ -1,30 +1,55 @@
 package usecase
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"go.uber.org/zap"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"testEM/internal/entities"
+	"testEM/pkg/breaker"
 	"time"
 )
 
+var (
+	ErrUpstreamUnavailable = errors.New("external API is unavailable")
+	ErrUpstreamTimeout     = errors.New("external API timed out")
+)
+
+type DetailClientOptions struct {
+	Timeout          time.Duration
+	Retries          int
+	Backoff          time.Duration
+	MaxBackoff       time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
 type detailClient struct {
 	externalUrl string
 	client      *http.Client
+	opts        DetailClientOptions
+	breaker     *breaker.Breaker
 	log         *zap.Logger
 }
 
-func NewDetailClient(externalUrl string, client *http.Client, log *zap.Logger) DetailClient {
+func NewDetailClient(externalUrl string, client *http.Client, opts DetailClientOptions, log *zap.Logger) DetailClient {
 	return &detailClient{
 		externalUrl: externalUrl,
 		client:      client,
+		opts:        opts,
+		breaker:     breaker.New(opts.BreakerThreshold, opts.BreakerCooldown),
 		log:         log,
 	}
 }
 
-func (dt *detailClient) GetSongDetails(track entities.AddSongDTO) (*entities.SongDetail, error) {
+// GetSongDetails stops retrying once ctx is done, the attempt cut short by
+// the caller is not counted as a failure of the external API.
+func (dt *detailClient) GetSongDetails(ctx context.Context, track entities.AddSongDTO) (*entities.SongDetail, error) {
 	u, err := url.Parse(dt.externalUrl)
 	if err != nil {
 		dt.log.Debug("Failed to read external url",
@@ -34,10 +59,65 @@ func (dt *detailClient) GetSongDetails(track entities.AddSongDTO) (*entities.Son
 		return nil, err
 	}
 
-	u.Query().Add("song", *track.Song)
-	u.Query().Add("group", *track.Group)
+	q := u.Query()
+	q.Set("song", *track.Song)
+	q.Set("group", *track.Group)
+	u.RawQuery = q.Encode()
+
+	if err = dt.breaker.Allow(); err != nil {
+		dt.log.Debug("Skipped request to external API",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, &entities.UpstreamError{Err: fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)}
+	}
+
+	var detail *entities.SongDetail
+	for attempt := 0; attempt <= dt.opts.Retries; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(dt.backoff(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+		if ctx.Err() != nil {
+			dt.breaker.Cancel()
+			return nil, ctx.Err()
+		}
+
+		detail, err = dt.fetch(ctx, u.String())
+		if ctx.Err() != nil {
+			dt.breaker.Cancel()
+			return nil, ctx.Err()
+		}
+		if err == nil || !isRetryable(err) {
+			break
+		}
+		dt.log.Debug("Retrying request to external API",
+			zap.Int("attempt", attempt+1),
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+
+	if err != nil && isRetryable(err) {
+		dt.breaker.Failure()
+		return nil, err
+	}
+	dt.breaker.Success()
+	return detail, err
+}
+
+func (dt *detailClient) fetch(ctx context.Context, u string) (*entities.SongDetail, error) {
+	if dt.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, dt.opts.Timeout)
+		defer cancel()
+	}
 
-	req, err := http.NewRequest("GET", u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
 	if err != nil {
 		dt.log.Debug("Failed to create request",
 			zap.String("message", err.Error()),
@@ -52,7 +132,19 @@ func (dt *detailClient) GetSongDetails(track entities.AddSongDTO) (*entities.Son
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		return nil, err
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, &entities.UpstreamError{Err: fmt.Errorf("%w: %w", ErrUpstreamTimeout, err)}
+		}
+		return nil, &entities.UpstreamError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		dt.log.Debug("External API responded with error status",
+			zap.Int("status", resp.StatusCode),
+			zap.Time("time", time.Now()),
+		)
+		return nil, &entities.UpstreamError{StatusCode: resp.StatusCode}
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -61,7 +153,10 @@ func (dt *detailClient) GetSongDetails(track entities.AddSongDTO) (*entities.Son
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		return nil, err
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, &entities.UpstreamError{Err: fmt.Errorf("%w: %w", ErrUpstreamTimeout, err)}
+		}
+		return nil, &entities.UpstreamError{Err: err}
 	}
 
 	var detail *entities.SongDetail
@@ -72,8 +167,32 @@ func (dt *detailClient) GetSongDetails(track entities.AddSongDTO) (*entities.Son
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		return nil, err
+		return nil, &entities.UpstreamError{Err: err}
 	}
 
 	return detail, err
 }
+
+// backoff doubles the delay for every following attempt up to MaxBackoff.
+func (dt *detailClient) backoff(attempt int) time.Duration {
+	d := dt.opts.Backoff << (attempt - 1)
+	if d <= 0 || (dt.opts.MaxBackoff > 0 && d > dt.opts.MaxBackoff) {
+		return dt.opts.MaxBackoff
+	}
+	return d
+}
+
+// isRetryable reports whether the error is caused by the upstream being
+// unhealthy: network failures, timeouts, 5xx responses and an open breaker.
+func isRetryable(err error) bool {
+	var upstreamErr *entities.UpstreamError
+	if !errors.As(err, &upstreamErr) {
+		return false
+	}
+	if upstreamErr.StatusCode != 0 {
+		return upstreamErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	return errors.Is(err, ErrUpstreamTimeout) || errors.Is(err, ErrUpstreamUnavailable) || errors.As(err, &netErr)
+}
@@ -0,0 +1,196 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testEM/internal/entities"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// enrichmentCaller makes the changes of enrichment workers.
+var enrichmentCaller = entities.Caller{Actor: entities.ActorEnrichment}
+
+type EnrichmentOptions struct {
+	Workers      int
+	PollInterval time.Duration
+	Lease        time.Duration
+	MaxAttempts  int
+	RetryDelay   time.Duration
+}
+
+// EnrichmentPool runs workers that take pending songs from the persisted
+// queue and fill them in with details from the external API.
+type EnrichmentPool struct {
+	uc   *Usecase
+	opts EnrichmentOptions
+	log  *zap.Logger
+	wg   sync.WaitGroup
+}
+
+func NewEnrichmentPool(uc *Usecase, opts EnrichmentOptions, log *zap.Logger) *EnrichmentPool {
+	return &EnrichmentPool{
+		uc:   uc,
+		opts: opts,
+		log:  log,
+	}
+}
+
+func (p *EnrichmentPool) Start(ctx context.Context) {
+	for i := 0; i < p.opts.Workers; i++ {
+		p.wg.Add(1)
+		go p.work(ctx)
+	}
+	p.log.Info("Started enrichment workers",
+		zap.Int("workers", p.opts.Workers),
+		zap.Time("time", time.Now()),
+	)
+}
+
+// Wait blocks until all workers have stopped after ctx is cancelled.
+func (p *EnrichmentPool) Wait() {
+	p.wg.Wait()
+}
+
+func (p *EnrichmentPool) work(ctx context.Context) {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			processed, err := p.uc.processEnrichmentJob(ctx, p.opts)
+			if err != nil {
+				p.log.Error("Failed to process enrichment job",
+					zap.String("message", err.Error()),
+					zap.Time("time", time.Now()),
+				)
+			}
+			if !processed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processEnrichmentJob handles a single queued job. It reports whether a job
+// was taken from the queue so the caller knows if it should poll again.
+func (uc *Usecase) processEnrichmentJob(ctx context.Context, opts EnrichmentOptions) (bool, error) {
+	job, err := uc.repos.Enrichment.ClaimJob(ctx, opts.Lease)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	song, err := uc.repos.Songs.GetSong(ctx, job.SongID)
+	if errors.Is(err, entities.ErrNotFound) {
+		// the song was deleted while queued, restoring it queues the job again
+		return true, uc.repos.Enrichment.CompleteJob(ctx, job.ID)
+	}
+	if err != nil {
+		return true, err
+	}
+	splitter, err := uc.splitterFor(job.Splitter)
+	if err != nil {
+		// the splitter was validated when the job was queued
+		splitter = uc.splitter
+	}
+
+	details, err := uc.client.GetSongDetails(ctx, entities.AddSongDTO{
+		Group: song.Group,
+		Song:  song.Song,
+	})
+	if ctx.Err() != nil {
+		// stopped while fetching, the job is claimed again once its lease expires
+		return true, err
+	}
+	if err != nil {
+		if job.Attempts+1 < opts.MaxAttempts && isRetryable(err) {
+			runAt := time.Now().Add(opts.RetryDelay << job.Attempts)
+			if retryErr := uc.repos.Enrichment.RetryJob(ctx, job.ID, runAt, err.Error()); retryErr != nil {
+				return true, errors.Join(err, retryErr)
+			}
+			return true, err
+		}
+
+		status := entities.EnrichmentFailed
+		failErr := uc.uow.Do(ctx, func(r Repositories) error {
+			before, err := songBefore(ctx, r, job.SongID)
+			if err != nil {
+				return err
+			}
+			if _, err := r.Songs.UpdateSong(ctx, job.SongID, entities.Song{EnrichmentStatus: &status}, nil); err != nil {
+				return err
+			}
+			if err := uc.recordSongChange(ctx, r, enrichmentCaller, job.SongID, entities.RevisionUpdate, entities.AuditSongEnrich, before); err != nil {
+				return err
+			}
+			return r.Enrichment.CompleteJob(ctx, job.ID)
+		})
+		return true, errors.Join(err, failErr)
+	}
+
+	status := entities.EnrichmentEnriched
+	update := entities.Song{
+		Link:             &details.Link,
+		EnrichmentStatus: &status,
+	}
+	// a malformed date is left unset rather than stored as zero
+	if date, err := time.Parse(DateLayout, details.ReleaseDate); err == nil {
+		update.ReleaseDate = &date
+	} else {
+		uc.log.Error("Failed to parse release date",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+	}
+
+	err = uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := songBefore(ctx, r, job.SongID)
+		if err != nil {
+			return err
+		}
+		if _, err = r.Songs.UpdateSong(ctx, job.SongID, update, nil); err != nil {
+			return err
+		}
+
+		// verses added through the API while the job was queued, or by an
+		// earlier run of the job, are kept instead of the fetched lyrics
+		count, err := r.Verses.CountVerses(ctx, job.SongID)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			uc.log.Info("Song already has verses, fetched lyrics are skipped",
+				zap.String("id", job.SongID),
+				zap.Time("time", time.Now()),
+			)
+		} else {
+			verses := splitter.Split(job.SongID, details.Content)
+			assignParts(nil, verses)
+			if err = r.Verses.AddVersesForSong(ctx, job.SongID, verses); err != nil {
+				return err
+			}
+		}
+		if err = uc.recordSongChange(ctx, r, enrichmentCaller, job.SongID, entities.RevisionUpdate, entities.AuditSongEnrich, before); err != nil {
+			return err
+		}
+		return r.Enrichment.CompleteJob(ctx, job.ID)
+	})
+	if err != nil {
+		return true, err
+	}
+
+	uc.log.Info("Enriched song",
+		zap.String("id", job.SongID),
+		zap.Time("time", time.Now()),
+	)
+	return true, nil
+}
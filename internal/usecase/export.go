@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"context"
+	"testEM/internal/entities"
+	"testEM/internal/validation"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// exportBatchSize is the number of songs fetched from the export cursor at once.
+const exportBatchSize = 500
+
+// ExportSongs calls fn for every song matching the options, in sort order, with
+// its verses. Songs are read in batches, so the whole catalog is never held in memory.
+// Pagination options are ignored.
+func (uc *Usecase) ExportSongs(ctx context.Context, options entities.SongSearchOptions, fn func(s *entities.ExportedSong) error) error {
+	if err := validation.Validate(&options); err != nil {
+		return err
+	}
+	if options.Lyrics != nil {
+		return entities.NewValidationError("lyrics", "is not supported by export")
+	}
+
+	count := 0
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		return r.Songs.ExportSongs(ctx, &options, exportBatchSize, func(s *entities.ExportedSong) error {
+			count++
+			return fn(s)
+		})
+	})
+	if err != nil {
+		uc.log.Error("Failed to export songs",
+			zap.Int("exported", count),
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+
+	uc.log.Info("Exported songs",
+		zap.Int("exported", count),
+		zap.Time("time", time.Now()),
+	)
+	return err
+}
@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testEM/internal/entities"
+	"testEM/internal/validation"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// normalizeGroupName collapses whitespace so "Muse" and " muse " name the same group,
+// case is handled by the unique index on lower(name).
+func normalizeGroupName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+func (uc *Usecase) GetGroups(ctx context.Context, options entities.GroupSearchOptions) (entities.GroupsWrapper, error) {
+	if err := validation.Validate(&options); err != nil {
+		return entities.GroupsWrapper{}, err
+	}
+
+	groups, page, err := uc.repos.Groups.GetGroups(ctx, &options)
+	if err != nil {
+		uc.log.Error("Failed to get groups",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return entities.GroupsWrapper{}, err
+	}
+
+	uc.log.Info("Recieved list of groups",
+		zap.Time("time", time.Now()),
+	)
+	resp := entities.GroupsWrapper{
+		Groups: groups,
+		Page:   page,
+	}
+	return resp, err
+}
+
+func (uc *Usecase) GetGroup(ctx context.Context, id string) (*entities.Group, error) {
+	g, err := uc.repos.Groups.GetGroup(ctx, id)
+	if err != nil {
+		uc.log.Error("Failed to get group",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	uc.log.Info("Recieved group",
+		zap.Time("time", time.Now()),
+	)
+	return g, err
+}
+
+func (uc *Usecase) AddGroup(ctx context.Context, dto entities.GroupDTO, caller entities.Caller) (*entities.Group, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
+	name := normalizeGroupName(*dto.Name)
+	if name == "" {
+		return nil, entities.NewValidationError("name", "must not be blank")
+	}
+
+	var g *entities.Group
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		var err error
+		g, err = r.Groups.AddGroup(ctx, name)
+		if err != nil {
+			uc.log.Error("Failed to add group",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditGroupAdd, entities.AuditGroup, *g.ID, nil, g)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Added group",
+		zap.Time("time", time.Now()),
+	)
+	return g, err
+}
+
+// RenameGroup renames the group together with the name stored on its songs,
+// the change is recorded in the history of every renamed song.
+func (uc *Usecase) RenameGroup(ctx context.Context, id string, dto entities.GroupDTO, caller entities.Caller) (*entities.Group, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
+	name := normalizeGroupName(*dto.Name)
+	if name == "" {
+		return nil, entities.NewValidationError("name", "must not be blank")
+	}
+
+	var g *entities.Group
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := r.Groups.GetGroup(ctx, id)
+		if err != nil {
+			return err
+		}
+		ids, err := r.Songs.GetGroupSongIDs(ctx, id)
+		if err != nil {
+			return err
+		}
+		songsBefore := make([]*entities.SongSnapshot, 0, len(ids))
+		for _, songID := range ids {
+			s, err := songBefore(ctx, r, songID)
+			if err != nil {
+				return err
+			}
+			songsBefore = append(songsBefore, s)
+		}
+
+		g, err = r.Groups.RenameGroup(ctx, id, name)
+		if err != nil {
+			uc.log.Error("Failed to rename group",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		err = r.Songs.RenameGroupSongs(ctx, *g.ID, *g.Name)
+		if err != nil {
+			uc.log.Error("Failed to rename group in songs",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		for i, songID := range ids {
+			if err := uc.recordSongChange(ctx, r, caller, songID, entities.RevisionUpdate, entities.AuditSongRegroup, songsBefore[i]); err != nil {
+				return err
+			}
+		}
+		return uc.audit(ctx, r, caller, entities.AuditGroupRename, entities.AuditGroup, id, before, g)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Renamed group",
+		zap.Time("time", time.Now()),
+	)
+	return g, err
+}
+
+// DeleteGroup fails with a conflict while the group still has songs.
+func (uc *Usecase) DeleteGroup(ctx context.Context, id string, caller entities.Caller) error {
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := r.Groups.GetGroup(ctx, id)
+		if err != nil {
+			return err
+		}
+		err = r.Groups.DeleteGroup(ctx, id)
+		if err != nil {
+			uc.log.Error("Failed to delete group",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditGroupDelete, entities.AuditGroup, id, before, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.log.Info("Deleted group",
+		zap.Time("time", time.Now()),
+	)
+	return err
+}
+
+func (uc *Usecase) GetGroupSongs(ctx context.Context, id string, options entities.SongSearchOptions) (entities.SongsWrapper, error) {
+	if _, err := uc.GetGroup(ctx, id); err != nil {
+		return entities.SongsWrapper{}, err
+	}
+
+	options.GroupID = &id
+	return uc.GetSongsWithFilters(ctx, options)
+}
+
+// resolveGroup replaces the group name of the song with the canonical one,
+// creating the group on first use.
+func (uc *Usecase) resolveGroup(ctx context.Context, r Repositories, s *entities.Song) error {
+	if s.Group == nil {
+		return nil
+	}
+	name := normalizeGroupName(*s.Group)
+	if name == "" {
+		return entities.NewValidationError("group", "must not be blank")
+	}
+
+	g, err := r.Groups.EnsureGroup(ctx, name)
+	if err != nil {
+		uc.log.Error("Failed to ensure group",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return err
+	}
+	s.Group = g.Name
+	s.GroupID = g.ID
+	return nil
+}
@@ -0,0 +1,401 @@
+package usecase
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testEM/internal/entities"
+	"testEM/internal/validation"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type ImportOptions struct {
+	Workers   int
+	BatchSize int
+	MaxRows   int
+}
+
+// Importer adds songs in bulk: records missing details are enriched through
+// the external API by a bounded number of workers, then stored in batches.
+type Importer struct {
+	uc   *Usecase
+	opts ImportOptions
+	log  *zap.Logger
+}
+
+func NewImporter(uc *Usecase, opts ImportOptions, log *zap.Logger) *Importer {
+	return &Importer{
+		uc:   uc,
+		opts: opts,
+		log:  log,
+	}
+}
+
+type importRow struct {
+	record entities.ImportRecord
+	result *entities.ImportRowResult
+	song   entities.Song
+	lyrics string
+}
+
+func (r *importRow) fail(err error) {
+	r.result.Status = entities.ImportFailed
+	r.result.Error = err.Error()
+
+	var validationErr *entities.ValidationError
+	if errors.As(err, &validationErr) {
+		r.result.Errors = validationErr.Violations
+	}
+}
+
+// Import reads records in the given format and returns the outcome of every row.
+// Lyrics are split into verses with the named splitter, the configured one when empty.
+// Created songs are recorded in history and audit log on behalf of caller.
+// An error is returned only when the input can't be read as a whole.
+func (im *Importer) Import(ctx context.Context, r io.Reader, format string, splitterName string, caller entities.Caller) (*entities.ImportReport, error) {
+	splitter, err := im.uc.splitterFor(&splitterName)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := im.decode(r, format)
+	if err != nil {
+		im.log.Error("Failed to decode import",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	pending := make([]*importRow, 0, len(rows))
+	for _, row := range rows {
+		if row.result.Status == entities.ImportFailed {
+			continue
+		}
+		if err := validation.Validate(&row.record); err != nil {
+			row.fail(err)
+			continue
+		}
+		pending = append(pending, row)
+	}
+
+	im.enrich(ctx, pending)
+
+	ready := make([]*importRow, 0, len(pending))
+	for _, row := range pending {
+		if row.result.Status != entities.ImportFailed {
+			ready = append(ready, row)
+		}
+	}
+	for start := 0; start < len(ready); start += im.batchSize() {
+		end := min(start+im.batchSize(), len(ready))
+		im.store(ctx, ready[start:end], splitter, caller)
+	}
+
+	report := &entities.ImportReport{
+		Total: len(rows),
+		Rows:  make([]*entities.ImportRowResult, 0, len(rows)),
+	}
+	for _, row := range rows {
+		if row.result.Status == entities.ImportFailed {
+			report.Failed++
+		} else {
+			report.Created++
+		}
+		report.Rows = append(report.Rows, row.result)
+	}
+
+	im.log.Info("Imported songs",
+		zap.Int("created", report.Created),
+		zap.Int("failed", report.Failed),
+		zap.Time("time", time.Now()),
+	)
+	return report, nil
+}
+
+func (im *Importer) batchSize() int {
+	if im.opts.BatchSize < 1 {
+		return 1
+	}
+	return im.opts.BatchSize
+}
+
+// enrich fetches details for records missing any of them and prepares songs
+// for insertion, at most Workers requests run at once.
+func (im *Importer) enrich(ctx context.Context, rows []*importRow) {
+	workers := max(im.opts.Workers, 1)
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+
+	for _, row := range rows {
+		rec := row.record
+		if rec.ReleaseDate != nil && rec.Link != nil && rec.Lyrics != nil {
+			im.prepare(row, nil)
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(row *importRow) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			details, err := im.uc.client.GetSongDetails(ctx, entities.AddSongDTO{Group: row.record.Group, Song: row.record.Song})
+			if err != nil {
+				im.log.Debug("Failed to get song details for import",
+					zap.Int("row", row.result.Row),
+					zap.String("message", err.Error()),
+					zap.Time("time", time.Now()),
+				)
+				row.fail(err)
+				return
+			}
+			im.prepare(row, details)
+		}(row)
+	}
+	wg.Wait()
+}
+
+// prepare builds the song from the record, fields given in the record
+// take precedence over details.
+func (im *Importer) prepare(row *importRow, details *entities.SongDetail) {
+	rec := row.record
+	status := entities.EnrichmentEnriched
+	row.song = entities.Song{
+		Group:            rec.Group,
+		Song:             rec.Song,
+		Link:             rec.Link,
+		EnrichmentStatus: &status,
+	}
+
+	releaseDate := rec.ReleaseDate
+	if details != nil {
+		if releaseDate == nil {
+			releaseDate = &details.ReleaseDate
+		}
+		if row.song.Link == nil {
+			row.song.Link = &details.Link
+		}
+		row.lyrics = details.Content
+	}
+	if rec.Lyrics != nil {
+		row.lyrics = *rec.Lyrics
+	}
+
+	if releaseDate != nil {
+		date, err := time.Parse(DateLayout, *releaseDate)
+		if err != nil {
+			im.log.Debug("Failed to parse release date for import",
+				zap.Int("row", row.result.Row),
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			row.fail(entities.NewValidationError("releaseDate", "must be in "+DateLayout+" format"))
+			return
+		}
+		row.song.ReleaseDate = &date
+	}
+}
+
+// store inserts the batch in one transaction. When it fails the rows are
+// stored one by one, so that a single bad row doesn't fail the others.
+func (im *Importer) store(ctx context.Context, rows []*importRow, splitter VerseSplitter, caller entities.Caller) {
+	added, err := im.insert(ctx, rows, splitter, caller)
+	if err == nil {
+		for i, s := range added {
+			rows[i].result.Status = entities.ImportCreated
+			rows[i].result.SongID = s.ID
+		}
+		return
+	}
+	if len(rows) == 1 || ctx.Err() != nil {
+		for _, row := range rows {
+			row.fail(err)
+		}
+		return
+	}
+
+	im.log.Debug("Failed to store import batch, storing rows one by one",
+		zap.String("message", err.Error()),
+		zap.Time("time", time.Now()),
+	)
+	for _, row := range rows {
+		im.store(ctx, []*importRow{row}, splitter, caller)
+	}
+}
+
+func (im *Importer) insert(ctx context.Context, rows []*importRow, splitter VerseSplitter, caller entities.Caller) ([]*entities.Song, error) {
+	var added []*entities.Song
+	err := im.uc.uow.Do(ctx, func(r Repositories) error {
+		groups := make(map[string]*entities.Group)
+		songs := make([]entities.Song, 0, len(rows))
+		for _, row := range rows {
+			song := row.song
+			name := normalizeGroupName(*song.Group)
+			g, ok := groups[strings.ToLower(name)]
+			if !ok {
+				if name == "" {
+					return entities.NewValidationError("group", "must not be blank")
+				}
+				var err error
+				g, err = r.Groups.EnsureGroup(ctx, name)
+				if err != nil {
+					return err
+				}
+				groups[strings.ToLower(name)] = g
+			}
+			song.Group = g.Name
+			song.GroupID = g.ID
+			songs = append(songs, song)
+		}
+
+		var err error
+		added, err = r.Songs.AddSongs(ctx, songs)
+		if err != nil {
+			return err
+		}
+		if len(added) != len(rows) {
+			return fmt.Errorf("added %d songs out of %d", len(added), len(rows))
+		}
+
+		var verses []*entities.Verse
+		for i, s := range added {
+			if strings.TrimSpace(rows[i].lyrics) != "" {
+				songVerses := splitter.Split(*s.ID, rows[i].lyrics)
+				assignParts(nil, songVerses)
+				verses = append(verses, songVerses...)
+			}
+		}
+		if err = r.Verses.AddVerses(ctx, verses); err != nil {
+			return err
+		}
+		for _, s := range added {
+			if err = im.uc.recordSongChange(ctx, r, caller, *s.ID, entities.RevisionCreate, entities.AuditSongImport, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return added, err
+}
+
+func (im *Importer) decode(r io.Reader, format string) ([]*importRow, error) {
+	// reading stops past the limit, the rest of the input is never buffered
+	limit := 0
+	if im.opts.MaxRows > 0 {
+		limit = im.opts.MaxRows + 1
+	}
+
+	var rows []*importRow
+	var err error
+	switch format {
+	case entities.ImportFormatCSV:
+		rows, err = decodeCSV(r, limit)
+	case entities.ImportFormatNDJSON:
+		rows, err = decodeNDJSON(r, limit)
+	default:
+		return nil, entities.NewValidationError("format", "must be one of csv ndjson")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if im.opts.MaxRows > 0 && len(rows) > im.opts.MaxRows {
+		return nil, entities.NewValidationError("body", fmt.Sprintf("must contain at most %d rows", im.opts.MaxRows))
+	}
+	return rows, nil
+}
+
+// decodeCSV expects a header naming the columns: group, song, releaseDate, link, lyrics.
+// Rows are numbered by their line in the input, at most limit rows are read when it's positive.
+func decodeCSV(r io.Reader, limit int) ([]*importRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, entities.NewValidationError("body", "must contain a header")
+		}
+		return nil, entities.NewValidationError("body", err.Error())
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"group", "song"} {
+		if _, ok := columns[name]; !ok {
+			return nil, entities.NewValidationError("body", "header must contain "+name+" column")
+		}
+	}
+
+	field := func(record []string, name string) *string {
+		i, ok := columns[strings.ToLower(name)]
+		if !ok || i >= len(record) || strings.TrimSpace(record[i]) == "" {
+			return nil
+		}
+		v := record[i]
+		return &v
+	}
+
+	var rows []*importRow
+	for limit <= 0 || len(rows) < limit {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, entities.NewValidationError("body", err.Error())
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, &importRow{
+			record: entities.ImportRecord{
+				Group:       field(record, "group"),
+				Song:        field(record, "song"),
+				ReleaseDate: field(record, "releaseDate"),
+				Link:        field(record, "link"),
+				Lyrics:      field(record, "lyrics"),
+			},
+			result: &entities.ImportRowResult{Row: line},
+		})
+	}
+	return rows, nil
+}
+
+// decodeNDJSON reads one record per line, blank lines are skipped.
+// Lines that are not valid JSON fail on their own. At most limit rows are read when it's positive.
+func decodeNDJSON(r io.Reader, limit int) ([]*importRow, error) {
+	reader := bufio.NewReader(r)
+
+	var rows []*importRow
+	for line := 1; limit <= 0 || len(rows) < limit; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, entities.NewValidationError("body", err.Error())
+		}
+
+		if len(bytes.TrimSpace(data)) > 0 {
+			row := &importRow{result: &entities.ImportRowResult{Row: line}}
+			if jsonErr := json.Unmarshal(data, &row.record); jsonErr != nil {
+				row.fail(entities.NewValidationError("row", jsonErr.Error()))
+			}
+			rows = append(rows, row)
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,309 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testEM/internal/entities"
+	"testEM/internal/validation"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func (uc *Usecase) GetPlaylists(ctx context.Context, options entities.PlaylistSearchOptions) (entities.PlaylistsWrapper, error) {
+	if err := validation.Validate(&options); err != nil {
+		return entities.PlaylistsWrapper{}, err
+	}
+
+	playlists, page, err := uc.repos.Playlists.GetPlaylists(ctx, &options)
+	if err != nil {
+		uc.log.Error("Failed to get playlists",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return entities.PlaylistsWrapper{}, err
+	}
+
+	uc.log.Info("Recieved list of playlists",
+		zap.Time("time", time.Now()),
+	)
+	resp := entities.PlaylistsWrapper{
+		Playlists: playlists,
+		Page:      page,
+	}
+	return resp, err
+}
+
+// GetPlaylist returns the playlist with its songs.
+func (uc *Usecase) GetPlaylist(ctx context.Context, id string) (*entities.Playlist, error) {
+	var p *entities.Playlist
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		var err error
+		p, err = uc.getPlaylist(ctx, r, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Recieved playlist",
+		zap.Time("time", time.Now()),
+	)
+	return p, err
+}
+
+func (uc *Usecase) getPlaylist(ctx context.Context, r Repositories, id string) (*entities.Playlist, error) {
+	p, err := r.Playlists.GetPlaylist(ctx, id)
+	if err != nil {
+		uc.log.Error("Failed to get playlist",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	p.Songs, err = r.Playlists.GetPlaylistSongs(ctx, id)
+	if err != nil {
+		uc.log.Error("Failed to get playlist songs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+	return p, err
+}
+
+func (uc *Usecase) AddPlaylist(ctx context.Context, dto entities.AddPlaylistDTO, caller entities.Caller) (*entities.Playlist, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
+
+	var p *entities.Playlist
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		var err error
+		p, err = r.Playlists.AddPlaylist(ctx, entities.Playlist{
+			Name:        dto.Name,
+			Description: dto.Description,
+		})
+		if err != nil {
+			uc.log.Error("Failed to add playlist",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditPlaylistAdd, entities.AuditPlaylist, *p.ID, nil, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Added playlist",
+		zap.Time("time", time.Now()),
+	)
+	return p, err
+}
+
+func (uc *Usecase) PatchPlaylist(ctx context.Context, id string, dto entities.PatchPlaylistDTO, caller entities.Caller) (*entities.Playlist, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
+
+	var p *entities.Playlist
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := r.Playlists.GetPlaylist(ctx, id)
+		if err != nil {
+			return err
+		}
+		p, err = r.Playlists.UpdatePlaylist(ctx, id, entities.Playlist{
+			Name:        dto.Name,
+			Description: dto.Description,
+		})
+		if err != nil {
+			uc.log.Error("Failed to update playlist",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditPlaylistPatch, entities.AuditPlaylist, id, before, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Updated playlist",
+		zap.Time("time", time.Now()),
+	)
+	return p, err
+}
+
+func (uc *Usecase) DeletePlaylist(ctx context.Context, id string, caller entities.Caller) error {
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := uc.getPlaylist(ctx, r, id)
+		if err != nil {
+			return err
+		}
+		err = r.Playlists.DeletePlaylist(ctx, id)
+		if err != nil {
+			uc.log.Error("Failed to delete playlist",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditPlaylistDelete, entities.AuditPlaylist, id, before, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.log.Info("Deleted playlist",
+		zap.Time("time", time.Now()),
+	)
+	return err
+}
+
+// AddPlaylistSong puts the song into the playlist at the given position,
+// appending it when the position is omitted.
+func (uc *Usecase) AddPlaylistSong(ctx context.Context, playlistID string, dto entities.AddPlaylistSongDTO, caller entities.Caller) (*entities.Playlist, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
+
+	var p *entities.Playlist
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := uc.getPlaylist(ctx, r, playlistID)
+		if err != nil {
+			return err
+		}
+		if _, err := r.Songs.GetSong(ctx, *dto.SongID); err != nil {
+			return err
+		}
+
+		count, err := r.Playlists.CountPlaylistSongs(ctx, playlistID)
+		if err != nil {
+			uc.log.Error("Failed to count playlist songs",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		num := count + 1
+		if dto.Number != nil {
+			num = *dto.Number
+		}
+		if num > count+1 {
+			return entities.NewValidationError("num", fmt.Sprintf("must be between 1 and %d", count+1))
+		}
+
+		err = r.Playlists.InsertPlaylistSong(ctx, playlistID, num, *dto.SongID)
+		if err != nil {
+			uc.log.Error("Failed to add song to playlist",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		p, err = uc.getPlaylist(ctx, r, playlistID)
+		if err != nil {
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditPlaylistSongAdd, entities.AuditPlaylist, playlistID, before, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Added song to playlist",
+		zap.Time("time", time.Now()),
+	)
+	return p, err
+}
+
+func (uc *Usecase) RemovePlaylistSong(ctx context.Context, playlistID string, num int, caller entities.Caller) error {
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := uc.getPlaylist(ctx, r, playlistID)
+		if err != nil {
+			return err
+		}
+		err = r.Playlists.RemovePlaylistSong(ctx, playlistID, num)
+		if err != nil {
+			uc.log.Error("Failed to remove song from playlist",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		after, err := uc.getPlaylist(ctx, r, playlistID)
+		if err != nil {
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditPlaylistSongRemove, entities.AuditPlaylist, playlistID, before, after)
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.log.Info("Removed song from playlist",
+		zap.Time("time", time.Now()),
+	)
+	return err
+}
+
+// MovePlaylistSong moves the entry to another position, entries in between are renumbered.
+func (uc *Usecase) MovePlaylistSong(ctx context.Context, playlistID string, num int, dto entities.MovePlaylistSongDTO, caller entities.Caller) (*entities.Playlist, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
+
+	var p *entities.Playlist
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := uc.getPlaylist(ctx, r, playlistID)
+		if err != nil {
+			return err
+		}
+		count, err := r.Playlists.CountPlaylistSongs(ctx, playlistID)
+		if err != nil {
+			uc.log.Error("Failed to count playlist songs",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		if num < 1 || num > count {
+			return &entities.NotFoundError{Resource: "playlist entry", ID: strconv.Itoa(num)}
+		}
+		if *dto.To > count {
+			return entities.NewValidationError("to", fmt.Sprintf("must be between 1 and %d", count))
+		}
+
+		err = r.Playlists.MovePlaylistSong(ctx, playlistID, num, *dto.To)
+		if err != nil {
+			uc.log.Error("Failed to move song in playlist",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		p, err = uc.getPlaylist(ctx, r, playlistID)
+		if err != nil {
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditPlaylistSongMove, entities.AuditPlaylist, playlistID, before, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Moved song in playlist",
+		zap.Time("time", time.Now()),
+	)
+	return p, err
+}
@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"testEM/internal/entities"
+	"testEM/internal/validation"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// purgeBatch is the number of songs looked up for purging at once.
+const purgeBatch = 100
+
+// purgeCaller makes the changes of the background purge.
+var purgeCaller = entities.Caller{Actor: entities.ActorPurge}
+
+type PurgeOptions struct {
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+// Purger periodically removes songs that were deleted longer than the
+// retention ago, after that they can't be restored.
+type Purger struct {
+	uc   *Usecase
+	opts PurgeOptions
+	log  *zap.Logger
+	wg   sync.WaitGroup
+}
+
+func NewPurger(uc *Usecase, opts PurgeOptions, log *zap.Logger) *Purger {
+	return &Purger{
+		uc:   uc,
+		opts: opts,
+		log:  log,
+	}
+}
+
+func (p *Purger) Start(ctx context.Context) {
+	p.wg.Add(1)
+	go p.work(ctx)
+	p.log.Info("Started purge of deleted songs",
+		zap.Duration("retention", p.opts.Retention),
+		zap.Time("time", time.Now()),
+	)
+}
+
+// Wait blocks until the purger has stopped after ctx is cancelled.
+func (p *Purger) Wait() {
+	p.wg.Wait()
+}
+
+func (p *Purger) work(ctx context.Context) {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := p.uc.PurgeDeletedSongs(ctx, time.Now().Add(-p.opts.Retention))
+		if err != nil {
+			p.log.Error("Failed to purge deleted songs",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+		}
+		if purged > 0 {
+			p.log.Info("Purged deleted songs",
+				zap.Int("songs", purged),
+				zap.Time("time", time.Now()),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeDeletedSongs permanently removes songs deleted before the given time
+// together with their places in albums and playlists. History of the songs
+// is kept. A song that fails to be purged is logged and skipped until the
+// next pass. It stops early when ctx is cancelled.
+func (uc *Usecase) PurgeDeletedSongs(ctx context.Context, before time.Time) (int, error) {
+	purged := 0
+	failed := make(map[string]bool)
+	for ctx.Err() == nil {
+		ids, err := uc.repos.Songs.GetPurgeableSongs(ctx, before, purgeBatch)
+		if err != nil {
+			return purged, err
+		}
+
+		batchPurged := 0
+		for _, id := range ids {
+			if failed[id] {
+				continue
+			}
+			err := uc.uow.Do(ctx, func(r Repositories) error {
+				if err := r.Albums.RemoveSongTracks(ctx, id); err != nil {
+					return err
+				}
+				if err := r.Playlists.RemoveSongFromPlaylists(ctx, id); err != nil {
+					return err
+				}
+				if err := r.Songs.PurgeSong(ctx, id); err != nil {
+					return err
+				}
+				return uc.audit(ctx, r, purgeCaller, entities.AuditSongPurge, entities.AuditSong, id, nil, nil)
+			})
+			if ctx.Err() != nil {
+				return purged, ctx.Err()
+			}
+			if err != nil {
+				uc.log.Error("Failed to purge deleted song",
+					zap.String("id", id),
+					zap.String("message", err.Error()),
+					zap.Time("time", time.Now()),
+				)
+				failed[id] = true
+				continue
+			}
+			purged++
+			batchPurged++
+		}
+		// failed songs are looked up again, a batch of only them ends the pass
+		if len(ids) < purgeBatch || batchPurged == 0 {
+			break
+		}
+	}
+	return purged, nil
+}
+
+// RestoreSong brings back the deleted song with its verses, it is queued for
+// enrichment again when that didn't finish before the deletion.
+func (uc *Usecase) RestoreSong(ctx context.Context, id string, caller entities.Caller) (*entities.Song, error) {
+	var s *entities.Song
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		var err error
+		s, err = r.Songs.RestoreSong(ctx, id)
+		if err != nil {
+			uc.log.Error("Failed to restore song",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		if s.EnrichmentStatus != nil && *s.EnrichmentStatus == entities.EnrichmentPending {
+			if err := r.Enrichment.EnqueueJob(ctx, id, nil); err != nil {
+				return err
+			}
+		}
+		return uc.recordSongChange(ctx, r, caller, id, entities.RevisionRestore, entities.AuditSongRestore, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Restored song",
+		zap.String("id", id),
+		zap.Time("time", time.Now()),
+	)
+	return s, err
+}
+
+// GetDeletedSongs lists songs that can still be restored, most recently
+// deleted first.
+func (uc *Usecase) GetDeletedSongs(ctx context.Context, options entities.DeletedSongSearchOptions) (entities.SongsWrapper, error) {
+	if err := validation.Validate(&options); err != nil {
+		return entities.SongsWrapper{}, err
+	}
+
+	songs, total, err := uc.repos.Songs.GetDeletedSongs(ctx, &options)
+	if err != nil {
+		uc.log.Error("Failed to get deleted songs",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return entities.SongsWrapper{}, err
+	}
+
+	uc.log.Info("Recieved deleted songs",
+		zap.Time("time", time.Now()),
+	)
+	return entities.SongsWrapper{
+		Songs: songs,
+		Page:  entities.Page{Total: &total},
+	}, err
+}
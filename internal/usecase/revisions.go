@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testEM/internal/entities"
+	"testEM/internal/validation"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// recordRevision stores the current state of the song with its difference
+// from the previous revision and returns it. It must run in the transaction
+// of the change, the song stays locked until it ends so that concurrent
+// changes don't take the same revision number.
+func (uc *Usecase) recordRevision(ctx context.Context, r Repositories, songID string, action string, actor string) (*entities.SongSnapshot, error) {
+	if err := r.Songs.LockSong(ctx, songID); err != nil {
+		uc.log.Error("Failed to lock song for revision",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	var snapshot *entities.SongSnapshot
+	if action != entities.RevisionDelete {
+		var err error
+		if snapshot, err = songSnapshot(ctx, r, songID); err != nil {
+			return nil, err
+		}
+	}
+
+	prev, err := r.Revisions.GetLatestRevision(ctx, songID)
+	if err != nil {
+		return nil, err
+	}
+	var prevSnapshot *entities.SongSnapshot
+	if prev != nil {
+		prevSnapshot = prev.Snapshot
+	}
+
+	_, err = r.Revisions.AddRevision(ctx, entities.Revision{
+		SongID:   songID,
+		Action:   action,
+		Actor:    actor,
+		Diff:     diffSnapshots(prevSnapshot, snapshot),
+		Snapshot: snapshot,
+	})
+	if err != nil {
+		uc.log.Error("Failed to record song revision",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+	return snapshot, err
+}
+
+func songSnapshot(ctx context.Context, r Repositories, songID string) (*entities.SongSnapshot, error) {
+	song, err := r.Songs.GetSong(ctx, songID)
+	if err != nil {
+		return nil, err
+	}
+	verses, _, err := r.Verses.GetVersesForSong(ctx, entities.VerseSearchOptions{SongID: &songID})
+	if err != nil {
+		return nil, err
+	}
+	return &entities.SongSnapshot{Song: *song, Verses: verses}, nil
+}
+
+// diffSnapshots lists changed fields of the song and its verses, a missing
+// snapshot stands for a song that doesn't exist.
+func diffSnapshots(old, cur *entities.SongSnapshot) []*entities.FieldChange {
+	fields := func(s *entities.SongSnapshot) map[string]*string {
+		res := make(map[string]*string)
+		if s == nil {
+			return res
+		}
+		res["group"] = s.Song.Group
+		res["song"] = s.Song.Song
+		res["link"] = s.Song.Link
+		res["enrichmentStatus"] = s.Song.EnrichmentStatus
+		if s.Song.ReleaseDate != nil {
+			date := s.Song.ReleaseDate.Format(DateLayout)
+			res["releaseDate"] = &date
+		}
+		for _, v := range s.Verses {
+			content := v.Content
+			res[fmt.Sprintf("verses[%d]", v.Number)] = &content
+			res[fmt.Sprintf("verses[%d].label", v.Number)] = v.Label
+		}
+		return res
+	}
+	oldFields, curFields := fields(old), fields(cur)
+
+	names := []string{"group", "song", "releaseDate", "link", "enrichmentStatus"}
+	verses := 0
+	for _, s := range []*entities.SongSnapshot{old, cur} {
+		if s != nil {
+			verses = max(verses, len(s.Verses))
+		}
+	}
+	for i := 1; i <= verses; i++ {
+		names = append(names, fmt.Sprintf("verses[%d]", i), fmt.Sprintf("verses[%d].label", i))
+	}
+
+	diff := make([]*entities.FieldChange, 0)
+	for _, name := range names {
+		o, c := oldFields[name], curFields[name]
+		if o == nil && c == nil || o != nil && c != nil && *o == *c {
+			continue
+		}
+		diff = append(diff, &entities.FieldChange{Field: name, Old: o, New: c})
+	}
+	return diff
+}
+
+// GetHistory lists revisions of the song newest first, history is kept
+// after the song is deleted.
+func (uc *Usecase) GetHistory(ctx context.Context, options entities.RevisionSearchOptions) (entities.RevisionsWrapper, error) {
+	if err := validation.Validate(&options); err != nil {
+		return entities.RevisionsWrapper{}, err
+	}
+
+	revisions, total, err := uc.repos.Revisions.GetRevisions(ctx, &options)
+	if err != nil {
+		uc.log.Error("Failed to get song revisions",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return entities.RevisionsWrapper{}, err
+	}
+	if total == 0 {
+		// songs added before history was recorded have none
+		if _, err := uc.repos.Songs.GetSong(ctx, *options.SongID); err != nil {
+			return entities.RevisionsWrapper{}, err
+		}
+	}
+
+	uc.log.Info("Recieved song history",
+		zap.Time("time", time.Now()),
+	)
+	return entities.RevisionsWrapper{
+		Revisions: revisions,
+		Page:      entities.Page{Total: &total},
+	}, err
+}
+
+// GetRevision returns the revision with the song as it was after it.
+func (uc *Usecase) GetRevision(ctx context.Context, songID string, num int) (*entities.Revision, error) {
+	rev, err := uc.repos.Revisions.GetRevision(ctx, songID, num)
+	if err != nil {
+		uc.log.Error("Failed to get song revision",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	uc.log.Info("Recieved song revision",
+		zap.Time("time", time.Now()),
+	)
+	return rev, err
+}
+
+// RollbackSong restores the song and its verses as they were after the
+// revision, recreating the song when it was deleted. Enrichment pending in the
+// revision is queued again. Timing of lines is not part of revisions and is
+// dropped. The rollback is recorded as a revision too.
+func (uc *Usecase) RollbackSong(ctx context.Context, songID string, num int, caller entities.Caller) (*entities.SongSnapshot, error) {
+	var snapshot *entities.SongSnapshot
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := songBefore(ctx, r, songID)
+		if err != nil {
+			return err
+		}
+
+		rev, err := r.Revisions.GetRevision(ctx, songID, num)
+		if err != nil {
+			return err
+		}
+		if rev.Snapshot == nil {
+			return entities.NewValidationError("num", "song was deleted in revision "+strconv.Itoa(num))
+		}
+
+		song := rev.Snapshot.Song
+		song.ID = &songID
+		if err := uc.resolveGroup(ctx, r, &song); err != nil {
+			return err
+		}
+		if _, err := r.Songs.UpsertSong(ctx, song); err != nil {
+			uc.log.Error("Failed to restore song",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		// a song pending before the rollback still has its job queued, a deleted
+		// or enriched one gets a new job to finish the restored enrichment
+		if isPending(&song) && (before == nil || !isPending(&before.Song)) {
+			if err := r.Enrichment.EnqueueJob(ctx, songID, nil); err != nil {
+				return err
+			}
+		}
+
+		if err := r.Verses.DeleteSong(ctx, songID); err != nil {
+			return err
+		}
+		verses := make([]*entities.Verse, 0, len(rev.Snapshot.Verses))
+		for _, v := range rev.Snapshot.Verses {
+			verses = append(verses, &entities.Verse{SongID: songID, Number: v.Number, Content: v.Content, Label: v.Label})
+		}
+		assignParts(nil, verses)
+		if err := r.Verses.AddVersesForSong(ctx, songID, verses); err != nil {
+			uc.log.Error("Failed to restore verses",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		snapshot, err = uc.recordRevision(ctx, r, songID, entities.RevisionRollback, caller.Actor)
+		if err != nil {
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditSongRollback, entities.AuditSong, songID, before, snapshot)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Rolled back song",
+		zap.String("id", songID),
+		zap.Int("revision", num),
+		zap.Time("time", time.Now()),
+	)
+	return snapshot, err
+}
+
+func isPending(s *entities.Song) bool {
+	return s.EnrichmentStatus != nil && *s.EnrichmentStatus == entities.EnrichmentPending
+}
@@ -0,0 +1,167 @@
+package usecase
+
+import (
+	"regexp"
+	"strings"
+	"testEM/internal/entities"
+)
+
+// VerseSplitter splits the text of a song into numbered verses.
+type VerseSplitter interface {
+	Split(songID string, content string) []*entities.Verse
+}
+
+// NewVerseSplitter returns the splitter registered under the name.
+func NewVerseSplitter(name string) (VerseSplitter, error) {
+	switch name {
+	case entities.SplitterBlankLine:
+		return BlankLineSplitter{}, nil
+	case entities.SplitterCRLF:
+		return CRLFSplitter{}, nil
+	case entities.SplitterSections:
+		return SectionSplitter{}, nil
+	}
+	return nil, entities.NewValidationError("splitter", "must be one of blank crlf sections")
+}
+
+// splitterFor returns the splitter requested by name, or the configured one.
+func (uc *Usecase) splitterFor(name *string) (VerseSplitter, error) {
+	if name == nil || *name == "" {
+		return uc.splitter, nil
+	}
+	return NewVerseSplitter(*name)
+}
+
+// BlankLineSplitter splits on "\n\n" the way songs were always split,
+// but drops verses left empty by trailing or repeated blank lines.
+type BlankLineSplitter struct{}
+
+func (BlankLineSplitter) Split(songID string, content string) []*entities.Verse {
+	var verses []*entities.Verse
+	for _, entry := range strings.Split(content, "\n\n") {
+		entry = strings.Trim(entry, "\n")
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		verses = appendVerse(verses, songID, entry, nil)
+	}
+	return verses
+}
+
+// CRLFSplitter normalizes \r\n and \r line endings and treats lines of
+// whitespace as blank before splitting on blank lines.
+type CRLFSplitter struct{}
+
+func (CRLFSplitter) Split(songID string, content string) []*entities.Verse {
+	var verses []*entities.Verse
+	for _, block := range blocks(normalizeNewlines(content)) {
+		verses = appendVerse(verses, songID, strings.Join(block, "\n"), nil)
+	}
+	return verses
+}
+
+var (
+	sectionHeaderRe = regexp.MustCompile(`^(?:\[([^\]]+)\]|\(([^)]+)\)|([^:]+):)$`)
+	sectionNumberRe = regexp.MustCompile(`\s*\d+$`)
+)
+
+// sectionLabels maps words of section headers to labels.
+var sectionLabels = []struct {
+	words []string
+	label string
+}{
+	{[]string{"pre-chorus", "prechorus", "pre chorus", "предприпев"}, entities.SectionPreChorus},
+	{[]string{"chorus", "refrain", "hook", "припев"}, entities.SectionChorus},
+	{[]string{"verse", "куплет"}, entities.SectionVerse},
+	{[]string{"bridge", "бридж"}, entities.SectionBridge},
+	{[]string{"intro", "вступление"}, entities.SectionIntro},
+	{[]string{"outro", "концовка"}, entities.SectionOutro},
+}
+
+// SectionSplitter starts a verse at every section header like [Chorus],
+// (Verse 2) or Bridge: and labels it, blank lines split sections further
+// keeping the label until the next header. Headers are not kept in content,
+// lines that only look like headers, like (oh oh oh), stay in the lyrics.
+type SectionSplitter struct{}
+
+func (SectionSplitter) Split(songID string, content string) []*entities.Verse {
+	var verses []*entities.Verse
+	var label *string
+	for _, block := range blocks(normalizeNewlines(content)) {
+		var lines []string
+		for _, line := range block {
+			l := sectionHeader(line)
+			if l == nil {
+				lines = append(lines, line)
+				continue
+			}
+			if len(lines) > 0 {
+				verses = appendVerse(verses, songID, strings.Join(lines, "\n"), label)
+				lines = nil
+			}
+			label = l
+		}
+		if len(lines) > 0 {
+			verses = appendVerse(verses, songID, strings.Join(lines, "\n"), label)
+		}
+	}
+	return verses
+}
+
+// sectionHeader returns the label of the section the line starts, or nil when
+// the line is lyrics. The whole bracketed or colon ended text must be a known
+// section word, optionally numbered.
+func sectionHeader(line string) *string {
+	m := sectionHeaderRe.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return nil
+	}
+	return sectionLabel(m[1] + m[2] + m[3])
+}
+
+func sectionLabel(header string) *string {
+	header = strings.Join(strings.Fields(strings.ToLower(header)), " ")
+	header = sectionNumberRe.ReplaceAllString(header, "")
+	for _, s := range sectionLabels {
+		for _, w := range s.words {
+			if header == w {
+				label := s.label
+				return &label
+			}
+		}
+	}
+	return nil
+}
+
+func normalizeNewlines(content string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(content, "\r\n", "\n"), "\r", "\n")
+}
+
+// blocks groups lines separated by lines of whitespace.
+func blocks(content string) [][]string {
+	var res [][]string
+	var block []string
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(block) > 0 {
+				res = append(res, block)
+				block = nil
+			}
+			continue
+		}
+		block = append(block, strings.TrimRight(line, " \t"))
+	}
+	if len(block) > 0 {
+		res = append(res, block)
+	}
+	return res
+}
+
+func appendVerse(verses []*entities.Verse, songID string, content string, label *string) []*entities.Verse {
+	return append(verses, &entities.Verse{
+		SongID:  songID,
+		Number:  len(verses) + 1,
+		Content: content,
+		Label:   label,
+	})
+}
@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"testEM/internal/entities"
+	"testing"
+)
+
+func TestSectionSplitter(t *testing.T) {
+	type verse struct {
+		content string
+		label   string
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    []verse
+	}{
+		{
+			name:    "lines looking like headers stay in lyrics",
+			content: "I sang it to the universe:\nand it sang back\n(oh oh oh)\nla la\n\n[Chorus]\nHooked on a feeling:\nhigh",
+			want: []verse{
+				{content: "I sang it to the universe:\nand it sang back\n(oh oh oh)\nla la"},
+				{content: "Hooked on a feeling:\nhigh", label: entities.SectionChorus},
+			},
+		},
+		{
+			name:    "numbered headers in every form",
+			content: "[Verse 1]\none\n(Pre-Chorus)\ntwo\nChorus 2:\nthree\n\nfour\n[Припев]\nпять",
+			want: []verse{
+				{content: "one", label: entities.SectionVerse},
+				{content: "two", label: entities.SectionPreChorus},
+				{content: "three", label: entities.SectionChorus},
+				{content: "four", label: entities.SectionChorus},
+				{content: "пять", label: entities.SectionChorus},
+			},
+		},
+		{
+			name:    "unknown bracketed text is lyrics",
+			content: "[Guitar Solo]\n[Verse]\none\r\n[Chorus x2]",
+			want: []verse{
+				{content: "[Guitar Solo]"},
+				{content: "one\n[Chorus x2]", label: entities.SectionVerse},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SectionSplitter{}.Split("song", tt.content)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Split() returned %d verses, want %d", len(got), len(tt.want))
+			}
+			for i, v := range got {
+				label := ""
+				if v.Label != nil {
+					label = *v.Label
+				}
+				if v.Number != i+1 || v.Content != tt.want[i].content || label != tt.want[i].label {
+					t.Errorf("verse %d = %d %q %q, want %q %q", i, v.Number, v.Content, label, tt.want[i].content, tt.want[i].label)
+				}
+			}
+		})
+	}
+}
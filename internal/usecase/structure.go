@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testEM/internal/entities"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// nearIdenticalRatio is the share of words two verses must have in common,
+// in order, to be taken for the same part of the song.
+const nearIdenticalRatio = 0.8
+
+// assignParts numbers parts of verses so that identical and near-identical
+// verses share one. Known verses of the song keep their parts.
+func assignParts(known []*entities.Verse, verses []*entities.Verse) {
+	seen := make([]*entities.Verse, 0, len(known)+len(verses))
+	last := 0
+	for _, v := range known {
+		seen = append(seen, v)
+		last = max(last, v.Part)
+	}
+
+	for _, v := range verses {
+		v.Part = 0
+		words := strings.Fields(normalizeLine(v.Content))
+		for _, s := range seen {
+			if s.Content == v.Content || similarWords(words, strings.Fields(normalizeLine(s.Content))) {
+				v.Part = s.Part
+				break
+			}
+		}
+		if v.Part == 0 {
+			last++
+			v.Part = last
+		}
+		seen = append(seen, v)
+	}
+}
+
+// similarWords compares word sequences by edit distance.
+func similarWords(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	distance := prev[len(b)]
+	return 1-float64(distance)/float64(max(len(a), len(b))) >= nearIdenticalRatio
+}
+
+// partName turns part 1, 2, ... 26, 27 into A, B, ... Z, AA.
+func partName(n int) string {
+	name := ""
+	for ; n > 0; n = (n - 1) / 26 {
+		name = string(rune('A'+(n-1)%26)) + name
+	}
+	return name
+}
+
+// GetStructure returns the form of the song with parts named by letters in
+// order of their first appearance.
+func (uc *Usecase) GetStructure(ctx context.Context, songID string) (*entities.SongStructure, error) {
+	if _, err := uc.repos.Songs.GetSong(ctx, songID); err != nil {
+		uc.log.Error("Failed to get song",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	verses, _, err := uc.repos.Verses.GetVersesForSong(ctx, entities.VerseSearchOptions{SongID: &songID})
+	if err != nil {
+		uc.log.Error("Failed to get verses for song",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	names := make(map[int]string)
+	form := make([]string, 0, len(verses))
+	structure := &entities.SongStructure{Verses: make([]*entities.StructureVerse, 0, len(verses))}
+	for _, v := range verses {
+		name, ok := names[v.Part]
+		if !ok {
+			name = partName(len(names) + 1)
+			names[v.Part] = name
+		}
+		form = append(form, name)
+		structure.Verses = append(structure.Verses, &entities.StructureVerse{Verse: v, PartName: name})
+	}
+	structure.Form = strings.Join(form, "-")
+	structure.Parts = len(names)
+
+	uc.log.Info("Recieved song structure",
+		zap.Time("time", time.Now()),
+	)
+	return structure, err
+}
@@ -0,0 +1,349 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testEM/internal/entities"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxTimingSize limits the size of uploaded timing files.
+	maxTimingSize = 1 << 20
+	// alignWindow is how many cues ahead a line is looked up by text,
+	// so a missing line doesn't consume the rest of the file.
+	alignWindow = 8
+)
+
+var (
+	lrcTagRe    = regexp.MustCompile(`^\[([^\]]*)\]`)
+	lrcTimeRe   = regexp.MustCompile(`^(\d+):(\d{1,2})(?:[.:](\d{1,3}))?$`)
+	lrcWordRe   = regexp.MustCompile(`<\d+:\d{1,2}(?:[.:]\d{1,3})?>`)
+	srtTimeRe   = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})[,.](\d{1,3})\s*-->\s*(\d+):(\d{2}):(\d{2})[,.](\d{1,3})`)
+	srtMarkupRe = regexp.MustCompile(`<[^>]*>|\{[^}]*\}`)
+)
+
+// cue is a timed line of the uploaded file.
+type cue struct {
+	start int
+	end   *int
+	text  string
+}
+
+// SyncLyrics parses lrc or srt timing, aligns its lines with the lines of
+// the song verses and replaces the stored lines of the song. Format is
+// detected from the content when empty.
+func (uc *Usecase) SyncLyrics(ctx context.Context, songID string, format string, r io.Reader, caller entities.Caller) (*entities.TimingReport, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxTimingSize+1))
+	if err != nil {
+		return nil, entities.NewValidationError("body", err.Error())
+	}
+	if len(data) > maxTimingSize {
+		return nil, entities.NewValidationError("body", fmt.Sprintf("must be at most %d bytes", maxTimingSize))
+	}
+	content := strings.TrimPrefix(string(data), "\ufeff")
+
+	if format == "" {
+		format = entities.TimingFormatLRC
+		if strings.Contains(content, "-->") {
+			format = entities.TimingFormatSRT
+		}
+	}
+
+	var cues []cue
+	switch format {
+	case entities.TimingFormatLRC:
+		cues, err = parseLRC(content)
+	case entities.TimingFormatSRT:
+		cues, err = parseSRT(content)
+	default:
+		return nil, entities.NewValidationError("format", "must be one of lrc srt")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(cues) == 0 {
+		return nil, entities.NewValidationError("body", "must contain timed lines")
+	}
+
+	report := &entities.TimingReport{}
+	err = uc.uow.Do(ctx, func(r Repositories) error {
+		if _, err := r.Songs.GetSong(ctx, songID); err != nil {
+			return err
+		}
+
+		verses, _, err := r.Verses.GetVersesForSong(ctx, entities.VerseSearchOptions{SongID: &songID})
+		if err != nil {
+			uc.log.Error("Failed to get verses for song",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		before, err := r.Lines.GetSongLines(ctx, songID)
+		if err != nil {
+			return err
+		}
+
+		lines := splitLines(verses)
+		report.Aligned = alignLines(lines, cues)
+		report.Lines = len(lines)
+		report.Verses = verses
+
+		err = r.Lines.ReplaceSongLines(ctx, songID, lines)
+		if err != nil {
+			uc.log.Error("Failed to replace song lines",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		return uc.audit(ctx, r, caller, entities.AuditLinesSync, entities.AuditSong, songID, before, lines)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Synced song lyrics",
+		zap.Int("lines", report.Lines),
+		zap.Int("aligned", report.Aligned),
+		zap.Time("time", time.Now()),
+	)
+	return report, err
+}
+
+// GetActiveLine returns the line sung at the given offset from the start of the song.
+func (uc *Usecase) GetActiveLine(ctx context.Context, songID string, offsetMs int) (*entities.Line, error) {
+	if offsetMs < 0 {
+		return nil, entities.NewValidationError("offset", "must not be negative")
+	}
+
+	l, err := uc.repos.Lines.GetActiveLine(ctx, songID, offsetMs)
+	if err != nil {
+		uc.log.Error("Failed to get active line",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	uc.log.Info("Recieved active line",
+		zap.Time("time", time.Now()),
+	)
+	return l, err
+}
+
+// splitLines attaches non blank lines of every verse to it, numbered from 1
+// within the verse.
+func splitLines(verses []*entities.Verse) []*entities.Line {
+	var lines []*entities.Line
+	for _, v := range verses {
+		v.Lines = nil
+		for _, text := range strings.Split(strings.ReplaceAll(v.Content, "\r\n", "\n"), "\n") {
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			l := &entities.Line{
+				VerseNumber: v.Number,
+				Number:      len(v.Lines) + 1,
+				Content:     text,
+			}
+			v.Lines = append(v.Lines, l)
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// alignLines sets timestamps of lines from cues and returns the number of
+// aligned lines. Lines are matched by text in order first, then the lines
+// left between two matches take the cues left between them by position when
+// there are as many of both.
+func alignLines(lines []*entities.Line, cues []cue) int {
+	matched := make([]int, len(lines))
+	next := 0
+	for i, l := range lines {
+		matched[i] = -1
+		text := normalizeLine(l.Content)
+		for j := next; j < len(cues) && j < next+alignWindow; j++ {
+			if normalizeLine(cues[j].text) == text {
+				matched[i] = j
+				next = j + 1
+				break
+			}
+		}
+	}
+
+	prevLine, prevCue := -1, -1
+	for i := 0; i <= len(lines); i++ {
+		if i < len(lines) && matched[i] == -1 {
+			continue
+		}
+		nextCue := len(cues)
+		if i < len(lines) {
+			nextCue = matched[i]
+		}
+		if gap := i - prevLine - 1; gap > 0 && gap == nextCue-prevCue-1 {
+			for k := 1; k <= gap; k++ {
+				matched[prevLine+k] = prevCue + k
+			}
+		}
+		prevLine, prevCue = i, nextCue
+	}
+
+	aligned := 0
+	for i, l := range lines {
+		if matched[i] == -1 {
+			continue
+		}
+		c := cues[matched[i]]
+		start := c.start
+		l.StartMs = &start
+		l.EndMs = c.end
+		aligned++
+	}
+	return aligned
+}
+
+// normalizeLine leaves only lowercased words so punctuation and spacing
+// differences don't prevent matching.
+func normalizeLine(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// parseLRC reads [mm:ss.xx] timed lines, a line may have several timestamps.
+// Metadata tags are skipped except offset, word timing of enhanced lrc is
+// dropped. A line ends when the next one starts, blank timed lines only mark
+// the end of the previous one.
+func parseLRC(content string) ([]cue, error) {
+	var cues []cue
+	offset := 0
+
+	sc := bufio.NewScanner(strings.NewReader(content))
+	sc.Buffer(make([]byte, 0, 64*1024), maxTimingSize)
+	for n := 1; sc.Scan(); n++ {
+		rest := strings.TrimSpace(sc.Text())
+		var starts []int
+		for {
+			m := lrcTagRe.FindStringSubmatch(rest)
+			if m == nil {
+				break
+			}
+			rest = rest[len(m[0]):]
+			if t := lrcTimeRe.FindStringSubmatch(m[1]); t != nil {
+				starts = append(starts, clockMs(0, t[1], t[2], t[3]))
+				continue
+			}
+			if key, value, ok := strings.Cut(m[1], ":"); ok && strings.EqualFold(strings.TrimSpace(key), "offset") {
+				v, err := strconv.Atoi(strings.TrimSpace(value))
+				if err != nil {
+					return nil, entities.NewValidationError("body", fmt.Sprintf("line %d: invalid offset", n))
+				}
+				offset = v
+			}
+		}
+
+		text := strings.TrimSpace(lrcWordRe.ReplaceAllString(rest, ""))
+		for _, start := range starts {
+			cues = append(cues, cue{start: start, text: text})
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, entities.NewValidationError("body", err.Error())
+	}
+
+	// positive offset makes lines appear earlier
+	for i := range cues {
+		cues[i].start = max(cues[i].start-offset, 0)
+	}
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].start < cues[j].start })
+	for i := 0; i+1 < len(cues); i++ {
+		end := cues[i+1].start
+		cues[i].end = &end
+	}
+	return nonBlankCues(cues), nil
+}
+
+// parseSRT reads subtitle blocks, lines of a multiline block share its time
+// evenly in order.
+func parseSRT(content string) ([]cue, error) {
+	var cues []cue
+	for _, block := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n") {
+		rows := strings.Split(strings.TrimSpace(block), "\n")
+		if len(rows) == 0 || rows[0] == "" {
+			continue
+		}
+		if !strings.Contains(rows[0], "-->") {
+			rows = rows[1:]
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		t := srtTimeRe.FindStringSubmatch(strings.TrimSpace(rows[0]))
+		if t == nil {
+			return nil, entities.NewValidationError("body", fmt.Sprintf("invalid timing %q", rows[0]))
+		}
+		start := clockMs(hoursMs(t[1]), t[2], t[3], t[4])
+		end := clockMs(hoursMs(t[5]), t[6], t[7], t[8])
+		if end < start {
+			return nil, entities.NewValidationError("body", fmt.Sprintf("timing %q ends before it starts", rows[0]))
+		}
+
+		var texts []string
+		for _, row := range rows[1:] {
+			if text := strings.TrimSpace(srtMarkupRe.ReplaceAllString(row, "")); text != "" {
+				texts = append(texts, text)
+			}
+		}
+		for i, text := range texts {
+			lineEnd := start + (end-start)*(i+1)/len(texts)
+			cues = append(cues, cue{
+				start: start + (end-start)*i/len(texts),
+				end:   &lineEnd,
+				text:  text,
+			})
+		}
+	}
+
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].start < cues[j].start })
+	return cues, nil
+}
+
+func nonBlankCues(cues []cue) []cue {
+	res := cues[:0]
+	for _, c := range cues {
+		if c.text != "" {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func hoursMs(hours string) int {
+	h, _ := strconv.Atoi(hours)
+	return h * int(time.Hour/time.Millisecond)
+}
+
+// clockMs converts minutes, seconds and a fraction of a second given with
+// 1 to 3 digits to milliseconds and adds base.
+func clockMs(base int, minutes, seconds, fraction string) int {
+	m, _ := strconv.Atoi(minutes)
+	s, _ := strconv.Atoi(seconds)
+	ms := 0
+	if fraction != "" {
+		ms, _ = strconv.Atoi((fraction + "00")[:3])
+	}
+	return base + m*int(time.Minute/time.Millisecond) + s*int(time.Second/time.Millisecond) + ms
+}
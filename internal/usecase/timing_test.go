@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"testEM/internal/entities"
+	"testing"
+)
+
+func ms(v int) *int {
+	return &v
+}
+
+func sameCues(got []cue, want []cue) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i].start != want[i].start || got[i].text != want[i].text {
+			return false
+		}
+		if (got[i].end == nil) != (want[i].end == nil) || got[i].end != nil && *got[i].end != *want[i].end {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseLRC(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []cue
+		wantErr bool
+	}{
+		{
+			name:    "several timestamps on a line",
+			content: "[00:01.00][00:05.50]la la\n[00:03.00]hey\n",
+			want: []cue{
+				{start: 1000, end: ms(3000), text: "la la"},
+				{start: 3000, end: ms(5500), text: "hey"},
+				{start: 5500, text: "la la"},
+			},
+		},
+		{
+			name:    "crlf line endings",
+			content: "[00:01.00]one\r\n[00:02.50]two\r\n",
+			want: []cue{
+				{start: 1000, end: ms(2500), text: "one"},
+				{start: 2500, text: "two"},
+			},
+		},
+		{
+			name:    "blank timed line ends the previous one",
+			content: "[00:01.00]one\n[00:02.00]\n[00:04.00]two",
+			want: []cue{
+				{start: 1000, end: ms(2000), text: "one"},
+				{start: 4000, text: "two"},
+			},
+		},
+		{
+			name:    "metadata skipped and short fraction",
+			content: "[ar:Muse]\n[ti:Supermassive Black Hole]\n[00:00.5]one",
+			want: []cue{
+				{start: 500, text: "one"},
+			},
+		},
+		{
+			name:    "offset moves lines earlier",
+			content: "[offset:500]\n[00:01.00]one\n[00:00.20]zero",
+			want: []cue{
+				{start: 0, end: ms(500), text: "zero"},
+				{start: 500, text: "one"},
+			},
+		},
+		{
+			name:    "word timing dropped",
+			content: "[00:01.00]<00:01.00>one <00:01.50>two",
+			want: []cue{
+				{start: 1000, text: "one two"},
+			},
+		},
+		{
+			name:    "invalid offset",
+			content: "[offset:soon]\n[00:01.00]one",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLRC(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLRC() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !sameCues(got, tt.want) {
+				t.Errorf("parseLRC() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSRT(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []cue
+		wantErr bool
+	}{
+		{
+			name:    "no trailing blank line",
+			content: "1\n00:00:01,000 --> 00:00:02,000\none\n\n2\n00:00:03,000 --> 00:00:04,500\ntwo",
+			want: []cue{
+				{start: 1000, end: ms(2000), text: "one"},
+				{start: 3000, end: ms(4500), text: "two"},
+			},
+		},
+		{
+			name:    "crlf line endings",
+			content: "1\r\n00:00:01,000 --> 00:00:02,000\r\none\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\ntwo\r\n\r\n",
+			want: []cue{
+				{start: 1000, end: ms(2000), text: "one"},
+				{start: 3000, end: ms(4000), text: "two"},
+			},
+		},
+		{
+			name:    "multiline block shares its time",
+			content: "1\n00:00:01,000 --> 00:00:03,000\none\n<i>two</i>\n",
+			want: []cue{
+				{start: 1000, end: ms(2000), text: "one"},
+				{start: 2000, end: ms(3000), text: "two"},
+			},
+		},
+		{
+			name:    "hours and dot separator",
+			content: "00:00:01.5 --> 01:00:00.000\none",
+			want: []cue{
+				{start: 1500, end: ms(3600000), text: "one"},
+			},
+		},
+		{
+			name:    "invalid timing",
+			content: "1\n00:01 --> 00:02\none",
+			wantErr: true,
+		},
+		{
+			name:    "ends before it starts",
+			content: "1\n00:00:03,000 --> 00:00:01,000\none",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSRT(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSRT() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !sameCues(got, tt.want) {
+				t.Errorf("parseSRT() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlignLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		cues  []string
+		// want holds the index of the cue every line takes, -1 when unaligned
+		want []int
+	}{
+		{
+			name:  "matched by text",
+			lines: []string{"Hello, world!", "again"},
+			cues:  []string{"hello world", "Again."},
+			want:  []int{0, 1},
+		},
+		{
+			name:  "same count matched by position",
+			lines: []string{"a", "b", "c"},
+			cues:  []string{"x", "y", "z"},
+			want:  []int{0, 1, 2},
+		},
+		{
+			name:  "fewer timings than lines",
+			lines: []string{"a", "b", "c"},
+			cues:  []string{"a", "c"},
+			want:  []int{0, -1, 1},
+		},
+		{
+			name:  "more timings than lines",
+			lines: []string{"a", "b"},
+			cues:  []string{"a", "x", "b", "y"},
+			want:  []int{0, 2},
+		},
+		{
+			name:  "more timings than lines without matches",
+			lines: []string{"a", "b"},
+			cues:  []string{"x", "y", "z"},
+			want:  []int{-1, -1},
+		},
+		{
+			name:  "gap between matches filled by position",
+			lines: []string{"a", "b", "c", "d"},
+			cues:  []string{"a", "x", "y", "d"},
+			want:  []int{0, 1, 2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := make([]*entities.Line, len(tt.lines))
+			for i, text := range tt.lines {
+				lines[i] = &entities.Line{Content: text}
+			}
+			cues := make([]cue, len(tt.cues))
+			for i, text := range tt.cues {
+				cues[i] = cue{start: (i + 1) * 1000, text: text}
+			}
+
+			wantAligned := 0
+			for _, j := range tt.want {
+				if j != -1 {
+					wantAligned++
+				}
+			}
+			if got := alignLines(lines, cues); got != wantAligned {
+				t.Errorf("alignLines() = %d, want %d", got, wantAligned)
+			}
+			for i, j := range tt.want {
+				switch {
+				case j == -1 && lines[i].StartMs != nil:
+					t.Errorf("line %d starts at %d, want unaligned", i, *lines[i].StartMs)
+				case j != -1 && (lines[i].StartMs == nil || *lines[i].StartMs != cues[j].start):
+					t.Errorf("line %d starts at %v, want %d", i, lines[i].StartMs, cues[j].start)
+				}
+			}
+		})
+	}
+}
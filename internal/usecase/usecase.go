@@ -1,8 +1,12 @@
 package usecase
 
 import (
-	"strings"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
 	"testEM/internal/entities"
+	"testEM/internal/validation"
 	"time"
 
 	"go.uber.org/zap"
@@ -13,39 +17,156 @@ const (
 )
 
 type Usecase struct {
-	songRepo  SongRepo
-	verseRepo VerseRepo
-	log       *zap.Logger
-	client    DetailClient
+	repos    Repositories
+	uow      UnitOfWork
+	log      *zap.Logger
+	client   DetailClient
+	splitter VerseSplitter
 }
 type DetailClient interface {
-	GetSongDetails(song entities.AddSongDTO) (*entities.SongDetail, error)
+	GetSongDetails(ctx context.Context, song entities.AddSongDTO) (*entities.SongDetail, error)
 }
 
 type SongRepo interface {
-	GetSongsWithFilters(opts *entities.SongSearchOptions) ([]*entities.Song, int, error)
-	DeleteSong(id string) error
-	UpdateSong(id string, s entities.Song) (*entities.Song, error)
-	AddSong(song entities.Song) (*entities.Song, error)
+	GetSongsWithFilters(ctx context.Context, opts *entities.SongSearchOptions) ([]*entities.Song, entities.Page, error)
+	SearchSongsByLyrics(ctx context.Context, opts *entities.SongSearchOptions) ([]*entities.Song, int, error)
+	GetSong(ctx context.Context, id string) (*entities.Song, error)
+	LockSong(ctx context.Context, id string) error
+	DeleteSong(ctx context.Context, id string, versions []int) error
+	UpdateSong(ctx context.Context, id string, s entities.Song, versions []int) (*entities.Song, error)
+	AddSong(ctx context.Context, song entities.Song) (*entities.Song, error)
+	AddSongs(ctx context.Context, songs []entities.Song) ([]*entities.Song, error)
+	UpsertSong(ctx context.Context, song entities.Song) (*entities.Song, error)
+	RestoreSong(ctx context.Context, id string) (*entities.Song, error)
+	GetDeletedSongs(ctx context.Context, opts *entities.DeletedSongSearchOptions) ([]*entities.Song, int, error)
+	GetPurgeableSongs(ctx context.Context, before time.Time, limit int) ([]string, error)
+	PurgeSong(ctx context.Context, id string) error
+	GetGroupSongIDs(ctx context.Context, groupId string) ([]string, error)
+	RenameGroupSongs(ctx context.Context, groupId string, name string) error
+	ExportSongs(ctx context.Context, opts *entities.SongSearchOptions, batch int, fn func(s *entities.ExportedSong) error) error
 }
 
 type VerseRepo interface {
-	GetVersesForSong(opts entities.VerseSearchOptions) ([]*entities.Verse, int, error)
-	AddVersesForSong(songId string, verses []*entities.Verse) error
-	DeleteSong(id string) error
+	GetVersesForSong(ctx context.Context, opts entities.VerseSearchOptions) ([]*entities.Verse, entities.Page, error)
+	AddVersesForSong(ctx context.Context, songId string, verses []*entities.Verse) error
+	AddVerses(ctx context.Context, verses []*entities.Verse) error
+	DeleteSong(ctx context.Context, id string) error
+	GetVerse(ctx context.Context, songId string, num int) (*entities.Verse, error)
+	CountVerses(ctx context.Context, songId string) (int, error)
+	UpdateVerse(ctx context.Context, verse *entities.Verse) (*entities.Verse, error)
+	InsertVerse(ctx context.Context, verse *entities.Verse) (*entities.Verse, error)
+	DeleteVerse(ctx context.Context, songId string, num int) error
+	MoveVerse(ctx context.Context, songId string, from, to int) error
+	DeleteUnusedTexts(ctx context.Context, songId string) error
 }
 
-func NewUsecase(sr SongRepo, vr VerseRepo, log *zap.Logger, client DetailClient) *Usecase {
+type LineRepo interface {
+	ReplaceSongLines(ctx context.Context, songId string, lines []*entities.Line) error
+	DeleteSongLines(ctx context.Context, songId string) error
+	DeleteVerseLines(ctx context.Context, songId string, num int) error
+	GetSongLines(ctx context.Context, songId string) ([]*entities.Line, error)
+	GetActiveLine(ctx context.Context, songId string, offsetMs int) (*entities.Line, error)
+}
+
+type RevisionRepo interface {
+	AddRevision(ctx context.Context, rev entities.Revision) (*entities.Revision, error)
+	GetLatestRevision(ctx context.Context, songId string) (*entities.Revision, error)
+	GetRevision(ctx context.Context, songId string, num int) (*entities.Revision, error)
+	GetRevisions(ctx context.Context, opts *entities.RevisionSearchOptions) ([]*entities.Revision, int, error)
+}
+
+type AuditRepo interface {
+	AddEntry(ctx context.Context, e entities.AuditEntry, before, after any) error
+	GetEntries(ctx context.Context, opts *entities.AuditSearchOptions) ([]*entities.AuditEntry, int, error)
+}
+
+type GroupRepo interface {
+	AddGroup(ctx context.Context, name string) (*entities.Group, error)
+	EnsureGroup(ctx context.Context, name string) (*entities.Group, error)
+	GetGroup(ctx context.Context, id string) (*entities.Group, error)
+	GetGroups(ctx context.Context, opts *entities.GroupSearchOptions) ([]*entities.Group, entities.Page, error)
+	RenameGroup(ctx context.Context, id string, name string) (*entities.Group, error)
+	DeleteGroup(ctx context.Context, id string) error
+}
+
+type AlbumRepo interface {
+	AddAlbum(ctx context.Context, album entities.Album) (*entities.Album, error)
+	GetAlbum(ctx context.Context, id string) (*entities.Album, error)
+	GetAlbums(ctx context.Context, opts *entities.AlbumSearchOptions) ([]*entities.Album, entities.Page, error)
+	UpdateAlbum(ctx context.Context, id string, album entities.Album) (*entities.Album, error)
+	DeleteAlbum(ctx context.Context, id string) error
+	GetTracks(ctx context.Context, albumId string) ([]*entities.AlbumTrack, error)
+	CountTracks(ctx context.Context, albumId string) (int, error)
+	AddTrack(ctx context.Context, albumId string, songId string, num int) error
+	RemoveTrack(ctx context.Context, albumId string, songId string) error
+	MoveTrack(ctx context.Context, albumId string, songId string, to int) error
+	RemoveSongTracks(ctx context.Context, songId string) error
+}
+
+type PlaylistRepo interface {
+	AddPlaylist(ctx context.Context, playlist entities.Playlist) (*entities.Playlist, error)
+	GetPlaylist(ctx context.Context, id string) (*entities.Playlist, error)
+	GetPlaylists(ctx context.Context, opts *entities.PlaylistSearchOptions) ([]*entities.Playlist, entities.Page, error)
+	UpdatePlaylist(ctx context.Context, id string, playlist entities.Playlist) (*entities.Playlist, error)
+	DeletePlaylist(ctx context.Context, id string) error
+	GetPlaylistSongs(ctx context.Context, playlistId string) ([]*entities.PlaylistSong, error)
+	CountPlaylistSongs(ctx context.Context, playlistId string) (int, error)
+	InsertPlaylistSong(ctx context.Context, playlistId string, num int, songId string) error
+	RemovePlaylistSong(ctx context.Context, playlistId string, num int) error
+	MovePlaylistSong(ctx context.Context, playlistId string, from, to int) error
+	RemoveSongFromPlaylists(ctx context.Context, songId string) error
+}
+
+type EnrichmentRepo interface {
+	EnqueueJob(ctx context.Context, songId string, splitter *string) error
+	ClaimJob(ctx context.Context, lease time.Duration) (*entities.EnrichmentJob, error)
+	CompleteJob(ctx context.Context, id string) error
+	RetryJob(ctx context.Context, id string, runAt time.Time, lastErr string) error
+}
+
+// Repositories are bound to the same transaction inside UnitOfWork.Do.
+type Repositories struct {
+	Songs      SongRepo
+	Verses     VerseRepo
+	Lines      LineRepo
+	Enrichment EnrichmentRepo
+	Groups     GroupRepo
+	Albums     AlbumRepo
+	Playlists  PlaylistRepo
+	Revisions  RevisionRepo
+	Audit      AuditRepo
+}
+
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(r Repositories) error) error
+}
+
+// NewUsecase takes repositories working outside of a transaction,
+// uow provides the same set bound to a transaction. Splitter is used for
+// songs added without choosing one.
+func NewUsecase(repos Repositories, uow UnitOfWork, log *zap.Logger, client DetailClient, splitter VerseSplitter) *Usecase {
 	return &Usecase{
-		songRepo:  sr,
-		verseRepo: vr,
-		log:       log,
-		client:    client,
+		repos:    repos,
+		uow:      uow,
+		log:      log,
+		client:   client,
+		splitter: splitter,
 	}
 }
 
-func (uc *Usecase) GetSongsWithFilters(options entities.SongSearchOptions) (entities.SongsWrapper, error) {
-	s, count, err := uc.songRepo.GetSongsWithFilters(&options)
+func (uc *Usecase) GetSongsWithFilters(ctx context.Context, options entities.SongSearchOptions) (entities.SongsWrapper, error) {
+	if err := validation.Validate(&options); err != nil {
+		return entities.SongsWrapper{}, err
+	}
+
+	if options.Lyrics != nil {
+		if options.Cursor != nil {
+			return entities.SongsWrapper{}, entities.NewValidationError("cursor", "is not supported with lyrics search, use page")
+		}
+		return uc.searchSongsByLyrics(ctx, options)
+	}
+
+	s, page, err := uc.repos.Songs.GetSongsWithFilters(ctx, &options)
 	if err != nil {
 		//if errors.Is(err, &repository.NotFoundErr{}) {
 		//	uc.log.Error("Songs not found",
@@ -64,13 +185,36 @@ func (uc *Usecase) GetSongsWithFilters(options entities.SongSearchOptions) (enti
 	)
 	resp := entities.SongsWrapper{
 		Songs: s,
-		Total: count,
+		Page:  page,
 	}
 	return resp, err
 }
 
-func (uc *Usecase) GetVerses(options entities.VerseSearchOptions) (entities.VersesWrapper, error) {
-	verses, count, err := uc.verseRepo.GetVersesForSong(options)
+func (uc *Usecase) searchSongsByLyrics(ctx context.Context, options entities.SongSearchOptions) (entities.SongsWrapper, error) {
+	s, count, err := uc.repos.Songs.SearchSongsByLyrics(ctx, &options)
+	if err != nil {
+		uc.log.Error("failed to search songs by lyrics",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return entities.SongsWrapper{}, err
+	}
+	uc.log.Info("Recieved list of songs matching lyrics",
+		zap.Time("time", time.Now()),
+	)
+	resp := entities.SongsWrapper{
+		Songs: s,
+		Page:  entities.Page{Total: &count},
+	}
+	return resp, err
+}
+
+func (uc *Usecase) GetVerses(ctx context.Context, options entities.VerseSearchOptions) (entities.VersesWrapper, error) {
+	if err := validation.Validate(&options); err != nil {
+		return entities.VersesWrapper{}, err
+	}
+
+	verses, page, err := uc.repos.Verses.GetVersesForSong(ctx, options)
 	if err != nil {
 		uc.log.Error("failed to get verses for song",
 			zap.String("message", err.Error()),
@@ -84,42 +228,77 @@ func (uc *Usecase) GetVerses(options entities.VerseSearchOptions) (entities.Vers
 	)
 	resp := entities.VersesWrapper{
 		Verses: verses,
-		Total:  count,
+		Page:   page,
 	}
 	return resp, err
 }
 
-func (uc *Usecase) DeleteSong(id string) error {
-	err := uc.songRepo.DeleteSong(id)
+// versionConflict tells a song changed since the given versions apart from a
+// missing one, after a write conditional on the versions matched no rows.
+func versionConflict(ctx context.Context, r Repositories, id string, versions []int, err error) error {
+	if versions == nil || !errors.Is(err, entities.ErrNotFound) {
+		return err
+	}
+	if _, getErr := r.Songs.GetSong(ctx, id); getErr != nil {
+		return err
+	}
+	return &entities.PreconditionFailedError{Resource: "song", ID: id}
+}
+
+// GetSong returns the song, its version is used as the ETag.
+func (uc *Usecase) GetSong(ctx context.Context, id string) (*entities.Song, error) {
+	s, err := uc.repos.Songs.GetSong(ctx, id)
 	if err != nil {
-		uc.log.Error("Failed to delete song from songs",
+		uc.log.Error("Failed to get song",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
-		return err
+		return nil, err
 	}
 
-	uc.log.Info("Deleted song from songs",
+	uc.log.Info("Recieved song",
 		zap.Time("time", time.Now()),
 	)
+	return s, err
+}
 
-	err = uc.verseRepo.DeleteSong(id)
+// DeleteSong hides the song, it can be restored until it is purged. When
+// versions are given the song must be at one of them.
+func (uc *Usecase) DeleteSong(ctx context.Context, id string, versions []int, caller entities.Caller) error {
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := songBefore(ctx, r, id)
+		if err != nil {
+			return err
+		}
+		if err := r.Songs.DeleteSong(ctx, id, versions); err != nil {
+			uc.log.Error("Failed to delete song from songs",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return versionConflict(ctx, r, id, versions, err)
+		}
+		return uc.recordSongChange(ctx, r, caller, id, entities.RevisionDelete, entities.AuditSongDelete, before)
+	})
 	if err != nil {
-		uc.log.Error("Failed to delete song text from verses",
-			zap.String("message", err.Error()),
-			zap.Time("time", time.Now()),
-		)
 		return err
 	}
 
-	uc.log.Info("Deleted song text from verses",
+	uc.log.Info("Deleted song",
+		zap.String("id", id),
+		zap.String("actor", caller.Actor),
 		zap.Time("time", time.Now()),
 	)
 
 	return err
 }
 
-func (uc *Usecase) PatchSong(id string, dto entities.PatchSongDTO) (*entities.Song, error) {
+// PatchSong updates the given fields of the song. When versions are given the
+// song must be at one of them.
+func (uc *Usecase) PatchSong(ctx context.Context, id string, dto entities.PatchSongDTO, versions []int, caller entities.Caller) (*entities.Song, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
+
 	s := entities.Song{
 		Group: dto.Group,
 		Song:  dto.Song,
@@ -132,16 +311,31 @@ func (uc *Usecase) PatchSong(id string, dto entities.PatchSongDTO) (*entities.So
 				zap.String("message", err.Error()),
 				zap.Time("time", time.Now()),
 			)
+			return nil, entities.NewValidationError("releaseDate", "must be in "+DateLayout+" format")
 		}
 		s.ReleaseDate = &date
 	}
-	resp, err := uc.songRepo.UpdateSong(id, s)
+	var resp *entities.Song
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := songBefore(ctx, r, id)
+		if err != nil {
+			return err
+		}
+		if err := uc.resolveGroup(ctx, r, &s); err != nil {
+			return err
+		}
 
+		resp, err = r.Songs.UpdateSong(ctx, id, s, versions)
+		if err != nil {
+			uc.log.Error("Failed to update song in songs",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return versionConflict(ctx, r, id, versions, err)
+		}
+		return uc.recordSongChange(ctx, r, caller, id, entities.RevisionUpdate, entities.AuditSongPatch, before)
+	})
 	if err != nil {
-		uc.log.Error("Failed to update song in songs",
-			zap.String("message", err.Error()),
-			zap.Time("time", time.Now()),
-		)
 		return nil, err
 	}
 
@@ -152,8 +346,16 @@ func (uc *Usecase) PatchSong(id string, dto entities.PatchSongDTO) (*entities.So
 	return resp, err
 }
 
-func (uc *Usecase) AddSong(dto entities.AddSongDTO) (*entities.Song, error) {
-	details, err := uc.client.GetSongDetails(dto)
+func (uc *Usecase) AddSong(ctx context.Context, dto entities.AddSongDTO, caller entities.Caller) (*entities.Song, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
+	splitter, err := uc.splitterFor(dto.Splitter)
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := uc.client.GetSongDetails(ctx, dto)
 	if err != nil {
 		uc.log.Error("Failed to get song details from external API",
 			zap.String("message", err.Error()),
@@ -174,52 +376,361 @@ func (uc *Usecase) AddSong(dto entities.AddSongDTO) (*entities.Song, error) {
 		)
 	}
 
+	status := entities.EnrichmentEnriched
+	track := entities.Song{
+		Group:            dto.Group,
+		Song:             dto.Song,
+		ReleaseDate:      &date,
+		Link:             &details.Link,
+		EnrichmentStatus: &status,
+	}
+
+	var s *entities.Song
+	err = uc.uow.Do(ctx, func(r Repositories) error {
+		if err := uc.resolveGroup(ctx, r, &track); err != nil {
+			return err
+		}
+
+		var err error
+		s, err = r.Songs.AddSong(ctx, track)
+		if err != nil {
+			uc.log.Error("Failed to add song in songs",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		verses := splitter.Split(*s.ID, details.Content)
+		assignParts(nil, verses)
+		err = r.Verses.AddVersesForSong(ctx, *s.ID, verses)
+		if err != nil {
+			uc.log.Error("Failed to add song text in verses",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		return uc.recordSongChange(ctx, r, caller, *s.ID, entities.RevisionCreate, entities.AuditSongAdd, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Added song with text",
+		zap.Time("time", time.Now()),
+	)
+
+	return s, err
+}
+
+// AddSongAsync stores the song right away with pending enrichment status
+// and leaves fetching of details and verses to the enrichment workers.
+func (uc *Usecase) AddSongAsync(ctx context.Context, dto entities.AddSongDTO, caller entities.Caller) (*entities.Song, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
+
+	status := entities.EnrichmentPending
 	track := entities.Song{
-		Group:       dto.Group,
-		Song:        dto.Song,
-		ReleaseDate: &date,
-		Link:        &details.Link,
+		Group:            dto.Group,
+		Song:             dto.Song,
+		EnrichmentStatus: &status,
+	}
+
+	var s *entities.Song
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		if err := uc.resolveGroup(ctx, r, &track); err != nil {
+			return err
+		}
+
+		var err error
+		s, err = r.Songs.AddSong(ctx, track)
+		if err != nil {
+			uc.log.Error("Failed to add song in songs",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		err = r.Enrichment.EnqueueJob(ctx, *s.ID, dto.Splitter)
+		if err != nil {
+			uc.log.Error("Failed to enqueue enrichment job",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		return uc.recordSongChange(ctx, r, caller, *s.ID, entities.RevisionCreate, entities.AuditSongAdd, nil)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	s, err := uc.songRepo.AddSong(track)
+	uc.log.Info("Added song pending enrichment",
+		zap.Time("time", time.Now()),
+	)
+	return s, err
+}
+
+func (uc *Usecase) GetVerse(ctx context.Context, songID string, num int) (*entities.Verse, error) {
+	v, err := uc.repos.Verses.GetVerse(ctx, songID, num)
 	if err != nil {
-		uc.log.Error("Failed to add song in songs",
+		uc.log.Error("Failed to get verse",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
 		return nil, err
 	}
 
-	uc.log.Info("Added song to songs",
+	uc.log.Info("Recieved verse",
 		zap.Time("time", time.Now()),
 	)
+	return v, err
+}
 
-	content := details.Content
-	contents := strings.Split(content, "\n\n")
+func (uc *Usecase) ReplaceVerse(ctx context.Context, songID string, num int, dto entities.ReplaceVerseDTO, caller entities.Caller) (*entities.Verse, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
 
-	var verses []*entities.Verse
-	for i, entry := range contents {
-		verses = append(verses,
-			&entities.Verse{
-				SongID:  *s.ID,
-				Number:  i + 1,
-				Content: entry,
-			})
+	var v *entities.Verse
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := songBefore(ctx, r, songID)
+		if err != nil {
+			return err
+		}
+		verses, _, err := r.Verses.GetVersesForSong(ctx, entities.VerseSearchOptions{SongID: &songID})
+		if err != nil {
+			uc.log.Error("Failed to get verses for song",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		known := make([]*entities.Verse, 0, len(verses))
+		for _, other := range verses {
+			if other.Number != num {
+				known = append(known, other)
+			}
+		}
+
+		v = &entities.Verse{SongID: songID, Number: num, Content: *dto.Content, Label: dto.Label}
+		assignParts(known, []*entities.Verse{v})
+		v, err = r.Verses.UpdateVerse(ctx, v)
+		if err != nil {
+			uc.log.Error("Failed to replace verse",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		// timing of the old text doesn't apply to the new one
+		err = r.Lines.DeleteVerseLines(ctx, songID, num)
+		if err != nil {
+			uc.log.Error("Failed to delete verse lines",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		if err = r.Verses.DeleteUnusedTexts(ctx, songID); err != nil {
+			return err
+		}
+		return uc.recordSongChange(ctx, r, caller, songID, entities.RevisionUpdate, entities.AuditVerseReplace, before)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	err = uc.verseRepo.AddVersesForSong(*s.ID, verses)
+	uc.log.Info("Replaced verse",
+		zap.Time("time", time.Now()),
+	)
+	return v, err
+}
+
+func (uc *Usecase) InsertVerse(ctx context.Context, songID string, dto entities.AddVerseDTO, caller entities.Caller) (*entities.Verse, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
+
+	var v *entities.Verse
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := songBefore(ctx, r, songID)
+		if err != nil {
+			return err
+		}
+		count, err := r.Verses.CountVerses(ctx, songID)
+		if err != nil {
+			uc.log.Error("Failed to count verses",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		num := count + 1
+		if dto.Number != nil {
+			num = *dto.Number
+		}
+		if num < 1 || num > count+1 {
+			return entities.NewValidationError("num", fmt.Sprintf("must be between 1 and %d", count+1))
+		}
+
+		verses, _, err := r.Verses.GetVersesForSong(ctx, entities.VerseSearchOptions{SongID: &songID})
+		if err != nil {
+			uc.log.Error("Failed to get verses for song",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		v = &entities.Verse{SongID: songID, Number: num, Content: *dto.Content, Label: dto.Label}
+		assignParts(verses, []*entities.Verse{v})
+		v, err = r.Verses.InsertVerse(ctx, v)
+		if err != nil {
+			uc.log.Error("Failed to insert verse",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		return uc.recordSongChange(ctx, r, caller, songID, entities.RevisionUpdate, entities.AuditVerseInsert, before)
+	})
 	if err != nil {
-		uc.log.Error("Failed to add song text in verses",
+		return nil, err
+	}
+
+	uc.log.Info("Inserted verse",
+		zap.Time("time", time.Now()),
+	)
+	return v, err
+}
+
+func (uc *Usecase) MoveVerse(ctx context.Context, songID string, num int, dto entities.MoveVerseDTO, caller entities.Caller) (*entities.Verse, error) {
+	if err := validation.Validate(&dto); err != nil {
+		return nil, err
+	}
+
+	var v *entities.Verse
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := songBefore(ctx, r, songID)
+		if err != nil {
+			return err
+		}
+		count, err := r.Verses.CountVerses(ctx, songID)
+		if err != nil {
+			uc.log.Error("Failed to count verses",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		if num < 1 || num > count {
+			return &entities.NotFoundError{Resource: "verse", ID: strconv.Itoa(num)}
+		}
+		if *dto.To > count {
+			return entities.NewValidationError("to", fmt.Sprintf("must be between 1 and %d", count))
+		}
+
+		err = r.Verses.MoveVerse(ctx, songID, num, *dto.To)
+		if err != nil {
+			uc.log.Error("Failed to move verse",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+
+		v, err = r.Verses.GetVerse(ctx, songID, *dto.To)
+		if err != nil {
+			return err
+		}
+		return uc.recordSongChange(ctx, r, caller, songID, entities.RevisionUpdate, entities.AuditVerseMove, before)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Info("Moved verse",
+		zap.Time("time", time.Now()),
+	)
+	return v, err
+}
+
+func (uc *Usecase) DeleteVerse(ctx context.Context, songID string, num int, caller entities.Caller) error {
+	err := uc.uow.Do(ctx, func(r Repositories) error {
+		before, err := songBefore(ctx, r, songID)
+		if err != nil {
+			return err
+		}
+		if err := r.Verses.DeleteVerse(ctx, songID, num); err != nil {
+			uc.log.Error("Failed to delete verse",
+				zap.String("message", err.Error()),
+				zap.Time("time", time.Now()),
+			)
+			return err
+		}
+		if err := r.Verses.DeleteUnusedTexts(ctx, songID); err != nil {
+			return err
+		}
+		return uc.recordSongChange(ctx, r, caller, songID, entities.RevisionUpdate, entities.AuditVerseDelete, before)
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.log.Info("Deleted verse",
+		zap.Time("time", time.Now()),
+	)
+	return err
+}
+
+// GetLyrics returns all verses of the song, unlike GetVerses it fails when
+// the song doesn't exist.
+func (uc *Usecase) GetLyrics(ctx context.Context, songID string) (*entities.Lyrics, error) {
+	song, err := uc.repos.Songs.GetSong(ctx, songID)
+	if err != nil {
+		uc.log.Error("Failed to get song",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
+		return nil, err
+	}
+
+	verses, _, err := uc.repos.Verses.GetVersesForSong(ctx, entities.VerseSearchOptions{SongID: &songID})
+	if err != nil {
+		uc.log.Error("Failed to get verses for song",
 			zap.String("message", err.Error()),
 			zap.Time("time", time.Now()),
 		)
+		return nil, err
+	}
 
+	lines, err := uc.repos.Lines.GetSongLines(ctx, songID)
+	if err != nil {
+		uc.log.Error("Failed to get lines for song",
+			zap.String("message", err.Error()),
+			zap.Time("time", time.Now()),
+		)
 		return nil, err
 	}
+	byNumber := make(map[int]*entities.Verse, len(verses))
+	for _, v := range verses {
+		byNumber[v.Number] = v
+	}
+	for _, l := range lines {
+		if v, ok := byNumber[l.VerseNumber]; ok {
+			v.Lines = append(v.Lines, l)
+		}
+	}
 
-	uc.log.Info("Added song text to verses",
+	uc.log.Info("Recieved song lyrics",
 		zap.Time("time", time.Now()),
 	)
-
-	return s, err
+	return &entities.Lyrics{Song: song, Verses: verses}, err
 }
@@ -0,0 +1,93 @@
+package validation
+
+import (
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testEM/internal/entities"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// DecodeQuery fills fields of the struct pointed to by dst from query
+// parameters named by their `query` tags, then validates it. Values that
+// can't be parsed are reported together with rule violations.
+//
+// Supported field types are *string, *int, *bool, []string and *time.Time,
+// the latter parsed with the layout from the `layout` tag. Lists accept both
+// repeated parameters and comma separated values.
+func DecodeQuery(values url.Values, dst any) error {
+	rv := reflect.ValueOf(dst).Elem()
+	rt := rv.Type()
+
+	var violations []entities.FieldViolation
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		name := sf.Tag.Get("query")
+		if name == "" {
+			continue
+		}
+		raw, ok := values[name]
+		if !ok || len(raw) == 0 || raw[0] == "" {
+			continue
+		}
+
+		field := rv.Field(i)
+		if field.Kind() == reflect.Slice {
+			var items []string
+			for _, r := range raw {
+				for _, item := range strings.Split(r, ",") {
+					if item = strings.TrimSpace(item); item != "" {
+						items = append(items, item)
+					}
+				}
+			}
+			field.Set(reflect.ValueOf(items))
+			continue
+		}
+
+		val, msg := parseValue(field.Type().Elem(), raw[0], sf.Tag.Get("layout"))
+		if msg != "" {
+			violations = append(violations, entities.FieldViolation{Field: name, Message: msg})
+			continue
+		}
+		ptr := reflect.New(field.Type().Elem())
+		ptr.Elem().Set(val)
+		field.Set(ptr)
+	}
+
+	violations = append(violations, check(dst)...)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &entities.ValidationError{Violations: violations}
+}
+
+func parseValue(t reflect.Type, raw string, layout string) (reflect.Value, string) {
+	switch {
+	case t == timeType:
+		d, err := time.Parse(layout, raw)
+		if err != nil {
+			return reflect.Value{}, "must be a date in " + layout + " format"
+		}
+		return reflect.ValueOf(d), ""
+	case t.Kind() == reflect.String:
+		return reflect.ValueOf(raw).Convert(t), ""
+	case t.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return reflect.Value{}, "must be an integer"
+		}
+		return reflect.ValueOf(n), ""
+	case t.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return reflect.Value{}, "must be a boolean"
+		}
+		return reflect.ValueOf(b), ""
+	default:
+		panic("validation: unsupported query field type " + t.String())
+	}
+}
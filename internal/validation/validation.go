@@ -0,0 +1,159 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testEM/internal/entities"
+	"time"
+	"unicode/utf8"
+)
+
+// Validate checks fields of the struct pointed to by v against the rules
+// declared in their `validate` tags and returns all violations at once,
+// or nil when v is valid.
+//
+// Supported rules:
+//
+//	required   value must be present and not blank
+//	notblank   value may be absent but must not be blank when present
+//	min=N      minimum number, string length or list size
+//	max=N      maximum number, string length or list size
+//	date=L     string must be a date in layout L
+//	url        string must be an absolute http(s) URL
+//	oneof=A B  value must be one of the space separated options
+func Validate(v any) error {
+	violations := check(v)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &entities.ValidationError{Violations: violations}
+}
+
+func check(v any) []entities.FieldViolation {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rt := rv.Type()
+
+	var violations []entities.FieldViolation
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+
+		name := fieldName(sf)
+		field := rv.Field(i)
+		for _, rule := range strings.Split(tag, ",") {
+			if msg := applyRule(rule, field); msg != "" {
+				violations = append(violations, entities.FieldViolation{Field: name, Message: msg})
+			}
+		}
+	}
+	return violations
+}
+
+func applyRule(rule string, field reflect.Value) string {
+	name, arg, _ := strings.Cut(rule, "=")
+
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			if name == "required" {
+				return "is required"
+			}
+			return ""
+		}
+		field = field.Elem()
+	}
+
+	switch name {
+	case "required", "notblank":
+		if field.Kind() == reflect.String && strings.TrimSpace(field.String()) == "" {
+			return "must not be blank"
+		}
+		if field.Kind() == reflect.Slice && field.Len() == 0 && name == "required" {
+			return "is required"
+		}
+	case "min", "max":
+		limit, err := strconv.Atoi(arg)
+		if err != nil {
+			panic(fmt.Sprintf("validation: bad %s argument %q", name, arg))
+		}
+		return checkBound(name, limit, field)
+	case "date":
+		if _, err := time.Parse(arg, field.String()); err != nil {
+			return "must be a date in " + arg + " format"
+		}
+	case "url":
+		u, err := url.ParseRequestURI(field.String())
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "must be an absolute http(s) URL"
+		}
+	case "oneof":
+		options := strings.Fields(arg)
+		if field.Kind() == reflect.Slice {
+			for i := 0; i < field.Len(); i++ {
+				if !contains(options, field.Index(i).String()) {
+					return "must contain only " + strings.Join(options, ", ")
+				}
+			}
+			return ""
+		}
+		if !contains(options, field.String()) {
+			return "must be one of " + strings.Join(options, ", ")
+		}
+	default:
+		panic("validation: unknown rule " + name)
+	}
+	return ""
+}
+
+func checkBound(name string, limit int, field reflect.Value) string {
+	var n int
+	var unit string
+	switch field.Kind() {
+	case reflect.String:
+		n, unit = utf8.RuneCountInString(field.String()), " characters"
+	case reflect.Slice:
+		n, unit = field.Len(), " items"
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		n = int(field.Int())
+	default:
+		return ""
+	}
+
+	if name == "min" && n < limit {
+		if unit != "" {
+			return fmt.Sprintf("must contain at least %d%s", limit, unit)
+		}
+		return fmt.Sprintf("must be at least %d", limit)
+	}
+	if name == "max" && n > limit {
+		if unit != "" {
+			return fmt.Sprintf("must contain at most %d%s", limit, unit)
+		}
+		return fmt.Sprintf("must be at most %d", limit)
+	}
+	return ""
+}
+
+// fieldName returns the name the client knows the field by.
+func fieldName(sf reflect.StructField) string {
+	for _, key := range []string{"json", "query"} {
+		if name, _, _ := strings.Cut(sf.Tag.Get(key), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+func contains(options []string, v string) bool {
+	for _, o := range options {
+		if o == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- lines reference verses by id, so they follow verses when those are renumbered
+CREATE TABLE IF NOT EXISTS lines (
+    verse_id INT NOT NULL REFERENCES verses (id) ON DELETE CASCADE,
+    num INT NOT NULL,
+    content TEXT NOT NULL,
+    start_ms INT,
+    end_ms INT,
+    PRIMARY KEY (verse_id, num)
+);
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE IF EXISTS lines;
@@ -0,0 +1,9 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+ALTER TABLE verses ADD COLUMN IF NOT EXISTS label VARCHAR (16);
+-- splitter requested for the song, the configured one is used when null
+ALTER TABLE enrichment_jobs ADD COLUMN IF NOT EXISTS splitter VARCHAR (16);
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE enrichment_jobs DROP COLUMN IF EXISTS splitter;
+ALTER TABLE verses DROP COLUMN IF EXISTS label;
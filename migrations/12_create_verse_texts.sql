@@ -0,0 +1,39 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- every distinct text of a song is stored once, verses are positions referencing it;
+-- texts of the same part are identical or near-identical
+CREATE TABLE IF NOT EXISTS verse_texts (
+    id serial PRIMARY KEY,
+    song_id INT NOT NULL REFERENCES songs (id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    part INT NOT NULL DEFAULT 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS verse_texts_song_id_content_idx on verse_texts using btree (song_id, md5(content));
+CREATE INDEX IF NOT EXISTS verse_texts_content_fts_idx on verse_texts using gin (to_tsvector('simple', content));
+
+-- verses without content are kept as blank texts
+INSERT INTO verse_texts (song_id, content)
+SELECT DISTINCT song_id, COALESCE(content, '') FROM verses WHERE song_id IS NOT NULL;
+
+-- existing songs get a part per distinct text in order of first appearance
+UPDATE verse_texts SET part = first.part
+FROM (
+    SELECT t.id, row_number() OVER (PARTITION BY t.song_id ORDER BY min(v.num)) AS part
+    FROM verse_texts t JOIN verses v ON v.song_id = t.song_id AND COALESCE(v.content, '') = t.content
+    GROUP BY t.id, t.song_id
+) first
+WHERE verse_texts.id = first.id;
+
+ALTER TABLE verses ADD COLUMN IF NOT EXISTS text_id INT REFERENCES verse_texts (id);
+UPDATE verses SET text_id = t.id FROM verse_texts t WHERE t.song_id = verses.song_id AND t.content = COALESCE(verses.content, '');
+ALTER TABLE verses ALTER COLUMN text_id SET NOT NULL;
+ALTER TABLE verses DROP COLUMN IF EXISTS content;
+CREATE INDEX IF NOT EXISTS verses_text_id_idx on verses using btree (text_id);
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE verses ADD COLUMN IF NOT EXISTS content TEXT;
+UPDATE verses SET content = t.content FROM verse_texts t WHERE t.id = verses.text_id;
+ALTER TABLE verses DROP COLUMN IF EXISTS text_id;
+DROP TABLE IF EXISTS verse_texts;
+CREATE INDEX IF NOT EXISTS verses_content_fts_idx on verses using gin (to_tsvector('simple', content));
@@ -0,0 +1,17 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- revisions are kept after the song is deleted, so song_id is not a foreign key
+CREATE TABLE IF NOT EXISTS song_revisions (
+    id serial PRIMARY KEY,
+    song_id INT NOT NULL,
+    num INT NOT NULL,
+    action VARCHAR (16) NOT NULL,
+    actor VARCHAR (256) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    diff JSONB NOT NULL,
+    snapshot JSONB,
+    CONSTRAINT song_revisions_song_id_num_key UNIQUE (song_id, num)
+);
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE IF EXISTS song_revisions;
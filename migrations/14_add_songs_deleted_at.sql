@@ -0,0 +1,10 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS songs_deleted_at_idx on songs using btree (deleted_at) WHERE deleted_at IS NOT NULL;
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS songs_deleted_at_idx;
+DELETE FROM songs WHERE deleted_at IS NOT NULL;
+ALTER TABLE songs DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,6 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE songs DROP COLUMN IF EXISTS version;
@@ -0,0 +1,33 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE IF NOT EXISTS audit_log (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    actor VARCHAR (256) NOT NULL,
+    action VARCHAR (32) NOT NULL,
+    resource VARCHAR (16) NOT NULL,
+    resource_id VARCHAR (64) NOT NULL,
+    before JSONB,
+    after JSONB,
+    request_id VARCHAR (64),
+    client_ip VARCHAR (64)
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx on audit_log using btree (created_at);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx on audit_log using btree (actor, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_resource_idx on audit_log using btree (resource, resource_id, created_at);
+
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +migrate StatementEnd
+
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE IF EXISTS audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
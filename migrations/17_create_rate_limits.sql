@@ -0,0 +1,41 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limits (
+    key VARCHAR (512) PRIMARY KEY,
+    tokens double precision NOT NULL,
+    updated_at timestamptz NOT NULL,
+    full_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_full_at_idx on rate_limits using btree (full_at);
+
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION rate_limit_take(bucket VARCHAR, rate double precision, burst double precision)
+RETURNS TABLE (remaining double precision, granted boolean) AS $$
+DECLARE
+    cur double precision;
+    last timestamptz;
+BEGIN
+    INSERT INTO rate_limits (key, tokens, updated_at, full_at) VALUES (bucket, burst, now(), now())
+        ON CONFLICT (key) DO NOTHING;
+    SELECT rl.tokens, rl.updated_at INTO cur, last FROM rate_limits rl WHERE rl.key = bucket FOR UPDATE;
+
+    cur := LEAST(burst, cur + GREATEST(EXTRACT(EPOCH FROM now() - last), 0) * rate);
+    granted := cur >= 1;
+    IF granted THEN
+        cur := cur - 1;
+    END IF;
+    remaining := cur;
+
+    UPDATE rate_limits
+        SET tokens = cur, updated_at = now(), full_at = now() + make_interval(secs => (burst - cur) / rate)
+        WHERE key = bucket;
+    RETURN NEXT;
+END;
+$$ LANGUAGE plpgsql;
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP FUNCTION IF EXISTS rate_limit_take(VARCHAR, double precision, double precision);
+DROP TABLE IF EXISTS rate_limits;
@@ -0,0 +1,16 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- verses added twice by enrichment jobs share numbers, they are numbered one after another
+UPDATE verses SET num = renumbered.num
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY song_id ORDER BY num, id) AS num
+    FROM verses
+    WHERE song_id IN (SELECT song_id FROM verses GROUP BY song_id, num HAVING count(*) > 1)
+) renumbered
+WHERE verses.id = renumbered.id;
+
+-- deferrable so that verses shifted within one statement are checked once it ends
+ALTER TABLE verses ADD CONSTRAINT verses_song_id_num_key UNIQUE (song_id, num) DEFERRABLE INITIALLY IMMEDIATE;
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE verses DROP CONSTRAINT IF EXISTS verses_song_id_num_key;
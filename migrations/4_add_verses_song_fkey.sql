@@ -0,0 +1,8 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+DELETE FROM verses WHERE NOT EXISTS (SELECT 1 FROM songs WHERE songs.id = verses.song_id);
+ALTER TABLE verses
+    ADD CONSTRAINT verses_song_id_fkey FOREIGN KEY (song_id) REFERENCES songs (id) ON DELETE CASCADE;
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE verses DROP CONSTRAINT IF EXISTS verses_song_id_fkey;
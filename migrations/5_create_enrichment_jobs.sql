@@ -0,0 +1,18 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS enrichment_status VARCHAR (16) NOT NULL DEFAULT 'enriched';
+
+CREATE TABLE IF NOT EXISTS enrichment_jobs (
+    id serial PRIMARY KEY,
+    song_id INT NOT NULL REFERENCES songs (id) ON DELETE CASCADE,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    run_at timestamptz NOT NULL DEFAULT now(),
+    locked_until timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS enrichment_jobs_run_at_idx on enrichment_jobs using btree (run_at);
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE IF EXISTS enrichment_jobs;
+ALTER TABLE songs DROP COLUMN IF EXISTS enrichment_status;
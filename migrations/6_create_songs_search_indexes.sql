@@ -0,0 +1,19 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS song_lower_idx on songs using btree (lower(song) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS group_lower_idx on songs using btree (lower(group_name) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS song_trgm_idx on songs using gin (lower(song) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS group_trgm_idx on songs using gin (lower(group_name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS song_sort_idx on songs using btree ((coalesce(song, '')), id);
+CREATE INDEX IF NOT EXISTS group_sort_idx on songs using btree ((coalesce(group_name, '')), id);
+CREATE INDEX IF NOT EXISTS release_date_sort_idx on songs using btree ((coalesce(release_date, '-infinity'::date)), id);
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS song_lower_idx;
+DROP INDEX IF EXISTS group_lower_idx;
+DROP INDEX IF EXISTS song_trgm_idx;
+DROP INDEX IF EXISTS group_trgm_idx;
+DROP INDEX IF EXISTS song_sort_idx;
+DROP INDEX IF EXISTS group_sort_idx;
+DROP INDEX IF EXISTS release_date_sort_idx;
@@ -0,0 +1,29 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE IF NOT EXISTS groups (
+    id serial PRIMARY KEY,
+    name VARCHAR (1024) NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS groups_name_idx on groups using btree (lower(name));
+
+INSERT INTO groups (name)
+SELECT DISTINCT ON (lower(btrim(regexp_replace(group_name, '\s+', ' ', 'g'))))
+       btrim(regexp_replace(group_name, '\s+', ' ', 'g'))
+FROM songs
+WHERE btrim(coalesce(group_name, '')) <> ''
+ORDER BY lower(btrim(regexp_replace(group_name, '\s+', ' ', 'g'))), id
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS group_id INT REFERENCES groups (id) ON DELETE RESTRICT;
+
+UPDATE songs SET group_id = groups.id, group_name = groups.name
+FROM groups
+WHERE lower(groups.name) = lower(btrim(regexp_replace(songs.group_name, '\s+', ' ', 'g')));
+
+CREATE INDEX IF NOT EXISTS group_id_idx on songs using btree (group_id);
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS group_id_idx;
+ALTER TABLE songs DROP COLUMN IF EXISTS group_id;
+DROP TABLE IF EXISTS groups;
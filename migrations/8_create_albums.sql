@@ -0,0 +1,24 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE IF NOT EXISTS albums (
+    id serial PRIMARY KEY,
+    group_id INT NOT NULL REFERENCES groups (id) ON DELETE RESTRICT,
+    title VARCHAR (1024) NOT NULL,
+    release_date date
+);
+
+-- track numbers are shifted by single statements, so uniqueness is checked at their end
+CREATE TABLE IF NOT EXISTS album_tracks (
+    album_id INT NOT NULL REFERENCES albums (id) ON DELETE CASCADE,
+    song_id INT NOT NULL REFERENCES songs (id) ON DELETE CASCADE,
+    num INT NOT NULL,
+    PRIMARY KEY (album_id, song_id),
+    CONSTRAINT album_tracks_num_key UNIQUE (album_id, num) DEFERRABLE INITIALLY IMMEDIATE
+);
+
+CREATE INDEX IF NOT EXISTS album_group_id_idx on albums using btree (group_id);
+CREATE INDEX IF NOT EXISTS album_tracks_song_id_idx on album_tracks using btree (song_id);
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE IF EXISTS album_tracks;
+DROP TABLE IF EXISTS albums;
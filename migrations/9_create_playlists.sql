@@ -0,0 +1,21 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE IF NOT EXISTS playlists (
+    id serial PRIMARY KEY,
+    name VARCHAR (1024) NOT NULL,
+    description TEXT
+);
+
+-- a song may appear in a playlist several times, entries are addressed by position
+CREATE TABLE IF NOT EXISTS playlist_songs (
+    playlist_id INT NOT NULL REFERENCES playlists (id) ON DELETE CASCADE,
+    num INT NOT NULL,
+    song_id INT NOT NULL REFERENCES songs (id) ON DELETE CASCADE,
+    CONSTRAINT playlist_songs_pkey PRIMARY KEY (playlist_id, num) DEFERRABLE INITIALLY IMMEDIATE
+);
+
+CREATE INDEX IF NOT EXISTS playlist_songs_song_id_idx on playlist_songs using btree (song_id);
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE IF EXISTS playlist_songs;
+DROP TABLE IF EXISTS playlists;
@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
 	"time"
 
@@ -32,12 +35,25 @@ func (o *Onion) AppendMiddleware(mw ...Function) {
 	o.middlewares = append(o.middlewares, mw...)
 }
 
+// With returns an onion applying mw inside the middlewares of o, used to add
+// middlewares to a group of routes.
+func (o *Onion) With(mw ...Function) *Onion {
+	inner := make([]Function, 0, len(o.middlewares)+len(mw))
+	inner = append(inner, mw...)
+	inner = append(inner, o.middlewares...)
+	return &Onion{
+		middlewares: inner,
+		log:         o.log,
+	}
+}
+
 func (o *Onion) LogRequestResponse(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		o.log.Info("Request recieved",
 			zap.String("method", r.Method),
 			zap.String("requestURI", r.RequestURI),
 			zap.String("host", r.Host),
+			zap.String("requestId", RequestIDFrom(r.Context())),
 			zap.Time("time", time.Now()),
 		)
 
@@ -56,3 +72,30 @@ func (o *Onion) Timer(next http.HandlerFunc) http.HandlerFunc {
 		o.duration = time.Since(start)
 	}
 }
+
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// RequestIDFrom returns the id put into the context by RequestID, empty when
+// there is none.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestID keeps the id sent by the client in X-Request-ID or makes a new
+// one, the id is returned in the response and put into the request context.
+func (o *Onion) RequestID(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 64 {
+			buf := make([]byte, 16)
+			if _, err := rand.Read(buf); err == nil {
+				id = hex.EncodeToString(buf)
+			}
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	}
+}
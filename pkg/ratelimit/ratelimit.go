@@ -0,0 +1,175 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRoute names the limit used by routes without their own one.
+const DefaultRoute = "default"
+
+// Limit of a token bucket: it holds up to Burst tokens and gains Rate tokens
+// per second, every request takes one. The zero Limit doesn't limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Window is the time an empty bucket takes to fill up.
+func (l Limit) Window() time.Duration {
+	return seconds(float64(l.Burst) / l.Rate)
+}
+
+// Take refills the bucket holding tokens for the elapsed time and takes a
+// token from it when there is one, it returns the tokens left.
+func (l Limit) Take(tokens float64, elapsed time.Duration) (float64, Result) {
+	tokens = math.Min(float64(l.Burst), tokens+elapsed.Seconds()*l.Rate)
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	return tokens, l.Result(tokens, allowed)
+}
+
+// Result describes the bucket left with tokens after the request.
+func (l Limit) Result(tokens float64, allowed bool) Result {
+	res := Result{
+		Limit:     l,
+		Allowed:   allowed,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     seconds((float64(l.Burst) - tokens) / l.Rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / l.Rate)
+	}
+	return res
+}
+
+type Result struct {
+	Limit     Limit
+	Allowed   bool
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, set when
+	// the request was rejected.
+	RetryAfter time.Duration
+}
+
+// Store keeps buckets by key and takes tokens from them atomically.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Limiter applies limits of routes to clients, every client has a bucket per route.
+type Limiter struct {
+	store  Store
+	limits map[string]Limit
+}
+
+func NewLimiter(store Store, limits map[string]Limit) *Limiter {
+	return &Limiter{
+		store:  store,
+		limits: limits,
+	}
+}
+
+// Take takes a token of the client for the route, the result is nil when
+// the route is not limited.
+func (l *Limiter) Take(ctx context.Context, route, client string) (*Result, error) {
+	limit, ok := l.limits[route]
+	if !ok {
+		limit, ok = l.limits[DefaultRoute]
+	}
+	if !ok || limit == (Limit{}) {
+		return nil, nil
+	}
+	res, err := l.store.Take(ctx, bucketKey(route, client), limit)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// bucketKey names the bucket of the client for the route. Routes are hashed,
+// a route taken from a long path would not fit into the store key.
+func bucketKey(route, client string) string {
+	sum := sha256.Sum256([]byte(route))
+	return hex.EncodeToString(sum[:16]) + " " + client
+}
+
+// ParseLimits reads limits of routes from comma separated route=limit
+// entries, e.g. "default=20/s:40,POST /api/v1/songs=30/m:5". A limit is
+// the number of requests per s, m or h with an optional burst after the
+// colon, the burst defaults to the number of requests. A route with limit
+// "none" is not limited.
+func ParseLimits(s string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, spec, ok := strings.Cut(entry, "=")
+		route = strings.Join(strings.Fields(route), " ")
+		if !ok || route == "" {
+			return nil, fmt.Errorf("rate limit entry %q must be route=limit", entry)
+		}
+		if strings.TrimSpace(spec) == "none" {
+			limits[route] = Limit{}
+			continue
+		}
+		limit, err := parseLimit(strings.TrimSpace(spec))
+		if err != nil {
+			return nil, fmt.Errorf("rate limit of %s: %w", route, err)
+		}
+		limits[route] = limit
+	}
+	return limits, nil
+}
+
+func parseLimit(spec string) (Limit, error) {
+	rate, burst, hasBurst := strings.Cut(spec, ":")
+	count, unit, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%q must be requests/unit[:burst]", spec)
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("requests in %q must be a positive number", spec)
+	}
+	var per time.Duration
+	switch unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("unit in %q must be one of s m h", spec)
+	}
+
+	limit := Limit{Rate: float64(n) / per.Seconds(), Burst: n}
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(burst)
+		if err != nil || limit.Burst < 1 {
+			return Limit{}, fmt.Errorf("burst in %q must be a positive number", spec)
+		}
+	}
+	return limit, nil
+}
+
+func seconds(s float64) time.Duration {
+	if math.IsInf(s, 0) || math.IsNaN(s) || s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}